@@ -0,0 +1,105 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Assets is the file system all assets are read from, with the layout of the
+// resources directory (e.g. "textures/stone.png"). It is the embedded resources
+// unless SetAssetLayers puts a mod directory and a pack file in front of them.
+var Assets fs.FS = embeddedResources()
+
+func embeddedResources() fs.FS {
+	sub, err := fs.Sub(Embedded, "resources")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// SetAssetLayers makes Assets look for a file in the mod directory modDir first,
+// then in the zip file pack and last in the embedded resources.
+// Empty names and a mod directory that does not exist are skipped.
+func SetAssetLayers(modDir, pack string) error {
+	var layers layeredFS
+	if modDir != "" {
+		if info, err := os.Stat(modDir); err == nil && info.IsDir() {
+			layers = append(layers, os.DirFS(modDir))
+		} else if err == nil {
+			return fmt.Errorf("mod directory %v is not a directory", modDir)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if pack != "" {
+		// the pack stays open while the game runs
+		zr, err := zip.OpenReader(pack)
+		if err != nil {
+			return err
+		}
+		layers = append(layers, zr)
+	}
+	Assets = append(layers, embeddedResources())
+	return nil
+}
+
+// layeredFS opens a file from the first of its file systems that has it.
+type layeredFS []fs.FS
+
+func (l layeredFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, fsys := range l {
+		f, err := fsys.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return seekable(f)
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// seekable reads files that can not seek, like the ones of a zip file, into memory.
+// Audio decoders need to seek to loop and rewind.
+func seekable(f fs.File) (fs.File, error) {
+	if _, ok := f.(io.Seeker); ok {
+		return f, nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		return f, nil
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{Reader: bytes.NewReader(data), info: info}, nil
+}
+
+type memFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLayeredFS(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, "pack")
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	pack, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := layeredFS{
+		fstest.MapFS{"a.txt": {Data: []byte("mod")}},
+		pack,
+		fstest.MapFS{"b.txt": {Data: []byte("base")}, "c.txt": {Data: []byte("base")}},
+	}
+	for name, want := range map[string]string{"a.txt": "mod", "b.txt": "pack", "c.txt": "base"} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil || string(data) != want {
+			t.Errorf("%v: got %q, %v, want %q", name, data, err, want)
+		}
+	}
+
+	// files of the pack can seek
+	f, err := fsys.Open("b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, ok := f.(io.Seeker); !ok {
+		t.Errorf("file of the pack can not seek")
+	}
+
+	if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for missing file, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,230 @@
+package loader
+
+import (
+	"math"
+
+	"github.com/harbdog/raycaster-go/geom"
+	"github.com/harbdog/raycaster-go/geom3d"
+)
+
+// WallHit is where a move line first touches a wall.
+type WallHit struct {
+	Point geom.Vector2
+	// Normal of the wall face that was hit, it points against the move
+	Normal geom.Vector2
+}
+
+// WallHit returns where line first touches a wall cell of the levels between
+// heights minZ and maxZ, walls are grown by clipDistance on every side.
+// Level n fills the heights n to n+1 and anything outside the map is a wall.
+//
+// Only the grid cells the line crosses and their neighbours are tested,
+// walking the grid with a DDA.
+func (m *Map) WallHit(line geom.Line, minZ, maxZ, clipDistance float64) (WallHit, bool) {
+	lo, hi := m.levelRange(minZ, maxZ)
+	p := [3]float64{line.X1, line.Y1, 0}
+	d := [3]float64{line.X2 - line.X1, line.Y2 - line.Y1, 0}
+
+	var hit WallHit
+	hitT := math.Inf(1)
+	ddaWalk(p, d, &hitT, func(x, y int) {
+		if !m.isWall(x, y, lo, hi) {
+			return
+		}
+		bt, axis, ok := lineBoxHit(p, d,
+			[3]float64{float64(x) - clipDistance, float64(y) - clipDistance, math.Inf(-1)},
+			[3]float64{float64(x+1) + clipDistance, float64(y+1) + clipDistance, math.Inf(1)})
+		if ok && bt < hitT {
+			hitT = bt
+			normal := faceNormal(d, axis)
+			hit = WallHit{
+				Point:  geom.Vector2{X: p[0] + bt*d[0], Y: p[1] + bt*d[1]},
+				Normal: geom.Vector2{X: normal.X, Y: normal.Y},
+			}
+		}
+	})
+	return hit, !math.IsInf(hitT, 1)
+}
+
+// WallHit3d is where a 3D move line first touches a wall.
+type WallHit3d struct {
+	Point geom3d.Vector3
+	// Normal of the wall face that was hit, it points against the move
+	Normal geom3d.Vector3
+}
+
+// WallHit3d returns where the line of an object reaching from below its
+// position to above it first touches a wall. As in WallHit walls are grown by
+// clipDistance, but each level is tested at the heights the line has while it
+// crosses the cell, so a line can pass over a wall of a lower level.
+func (m *Map) WallHit3d(line geom3d.Line3d, below, above, clipDistance float64) (WallHit3d, bool) {
+	lo, hi := m.levelRange(math.Min(line.Z1, line.Z2)-below, math.Max(line.Z1, line.Z2)+above)
+	top := len(m.levels) - 1
+	p := [3]float64{line.X1, line.Y1, line.Z1}
+	d := [3]float64{line.X2 - line.X1, line.Y2 - line.Y1, line.Z2 - line.Z1}
+
+	var hit WallHit3d
+	hitT := math.Inf(1)
+	testBox := func(x, y int, minZ, maxZ float64) {
+		bt, axis, ok := lineBoxHit(p, d,
+			[3]float64{float64(x) - clipDistance, float64(y) - clipDistance, minZ},
+			[3]float64{float64(x+1) + clipDistance, float64(y+1) + clipDistance, maxZ})
+		if ok && bt < hitT {
+			hitT = bt
+			hit = WallHit3d{
+				Point:  geom3d.Vector3{X: p[0] + bt*d[0], Y: p[1] + bt*d[1], Z: p[2] + bt*d[2]},
+				Normal: faceNormal(d, axis),
+			}
+		}
+	}
+	ddaWalk(p, d, &hitT, func(x, y int) {
+		if x < 0 || y < 0 || x >= m.width || y >= m.height {
+			testBox(x, y, math.Inf(-1), math.Inf(1))
+			return
+		}
+		for l := lo; l <= hi; l++ {
+			if m.Level(l)[x][y] <= 0 {
+				continue
+			}
+			// the lowest level reaches down and the highest one up without end
+			minZ, maxZ := float64(l)-above, float64(l+1)+below
+			if l == 0 {
+				minZ = math.Inf(-1)
+			}
+			if l == top {
+				maxZ = math.Inf(1)
+			}
+			testBox(x, y, minZ, maxZ)
+		}
+	})
+	return hit, !math.IsInf(hitT, 1)
+}
+
+// ddaWalk calls visit for the grid cells the line from p along d crosses in X
+// and Y and their neighbours, as walls grown by a clip distance reach into them.
+// It stops once the line is past hitT, the nearest hit visit found so far.
+func ddaWalk(p, d [3]float64, hitT *float64, visit func(x, y int)) {
+	cellX, cellY := int(math.Floor(p[0])), int(math.Floor(p[1]))
+
+	// DDA: param t of the line (0 to 1) where the next cell border is crossed in X and Y
+	stepX, tMaxX, tDeltaX := ddaAxis(p[0], d[0], cellX)
+	stepY, tMaxY, tDeltaY := ddaAxis(p[1], d[1], cellY)
+
+	for t := 0.0; t <= 1 && t <= *hitT; {
+		for x := cellX - 1; x <= cellX+1; x++ {
+			for y := cellY - 1; y <= cellY+1; y++ {
+				visit(x, y)
+			}
+		}
+
+		if tMaxX < tMaxY {
+			t = tMaxX
+			tMaxX += tDeltaX
+			cellX += stepX
+		} else {
+			t = tMaxY
+			tMaxY += tDeltaY
+			cellY += stepY
+		}
+	}
+}
+
+// levelRange returns the levels an object between heights minZ and maxZ is in.
+func (m *Map) levelRange(minZ, maxZ float64) (int, int) {
+	lo := int(math.Max(math.Floor(minZ), 0))
+	hi := int(math.Ceil(maxZ)) - 1
+	if hi < lo {
+		hi = lo
+	}
+	// levels above the highest one are the same as the highest one
+	if top := len(m.levels) - 1; hi > top {
+		hi = top
+		if lo > top {
+			lo = top
+		}
+	}
+	return lo, hi
+}
+
+// WallAt reports whether the cell is a wall on the ground level, outside the map is a wall.
+func (m *Map) WallAt(x, y int) bool {
+	return m.isWall(x, y, 0, 0)
+}
+
+func (m *Map) isWall(x, y, lo, hi int) bool {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return true
+	}
+	for l := lo; l <= hi; l++ {
+		if m.Level(l)[x][y] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ddaAxis returns the cell step, the line param of the first cell border and
+// the line param between cell borders along one axis.
+func ddaAxis(pos, d float64, cell int) (int, float64, float64) {
+	switch {
+	case d > 0:
+		return 1, (float64(cell+1) - pos) / d, 1 / d
+	case d < 0:
+		return -1, (float64(cell) - pos) / d, -1 / d
+	default:
+		return 0, math.Inf(1), math.Inf(1)
+	}
+}
+
+// lineBoxHit returns the line param where the line from p along d hits the
+// box and the axis of the hit face. A line starting inside the box hits it where it leaves.
+func lineBoxHit(p, d, min, max [3]float64) (float64, int, bool) {
+	enter, exit := math.Inf(-1), math.Inf(1)
+	enterAxis, exitAxis := 0, 0
+	for i := range p {
+		t0, t1, ok := slab(p[i], d[i], min[i], max[i])
+		if !ok {
+			return 0, 0, false
+		}
+		if t0 > enter {
+			enter, enterAxis = t0, i
+		}
+		if t1 < exit {
+			exit, exitAxis = t1, i
+		}
+	}
+	if enter > exit || exit < 0 {
+		return 0, 0, false
+	}
+
+	t, axis := enter, enterAxis
+	if enter < 0 {
+		t, axis = exit, exitAxis
+	}
+	if t > 1 {
+		return 0, 0, false
+	}
+	return t, axis, true
+}
+
+// faceNormal returns the normal of the box face on axis that a line along d hits.
+func faceNormal(d [3]float64, axis int) geom3d.Vector3 {
+	var n [3]float64
+	n[axis] = -math.Copysign(1, d[axis])
+	return geom3d.Vector3{X: n[0], Y: n[1], Z: n[2]}
+}
+
+// slab returns the line params where the line enters and leaves min to max along one axis.
+func slab(pos, d, min, max float64) (float64, float64, bool) {
+	if d == 0 {
+		if pos < min || pos > max {
+			return 0, 0, false
+		}
+		return math.Inf(-1), math.Inf(1), true
+	}
+	t0, t1 := (min-pos)/d, (max-pos)/d
+	if t0 > t1 {
+		t0, t1 = t1, t0
+	}
+	return t0, t1, true
+}
@@ -0,0 +1,99 @@
+package loader
+
+import (
+	"math"
+	"testing"
+
+	"github.com/harbdog/raycaster-go/geom"
+	"github.com/harbdog/raycaster-go/geom3d"
+)
+
+func grid(walls ...[2]int) [][]int {
+	g := make([][]int, 5)
+	for x := range g {
+		g[x] = make([]int, 5)
+	}
+	for _, w := range walls {
+		g[w[0]][w[1]] = 1
+	}
+	return g
+}
+
+func TestWallHit(t *testing.T) {
+	// (2, 2) is a wall on both levels, (3, 1) only on the upper one
+	m := &Map{width: 5, height: 5, levels: [][][]int{grid([2]int{2, 2}), grid([2]int{2, 2}, [2]int{3, 1})}}
+
+	tests := []struct {
+		name       string
+		line       geom.Line
+		minZ, maxZ float64
+		hit        bool
+		point      geom.Vector2
+		normal     geom.Vector2
+	}{
+		{"wall", geom.Line{X1: 0.5, Y1: 2.5, X2: 4.5, Y2: 2.5}, 0, 0.5,
+			true, geom.Vector2{X: 1.9, Y: 2.5}, geom.Vector2{X: -1}},
+		{"pass by", geom.Line{X1: 1.5, Y1: 0.5, X2: 1.5, Y2: 4.5}, 0, 0.5, false, geom.Vector2{}, geom.Vector2{}},
+		{"below upper wall", geom.Line{X1: 1.5, Y1: 1.5, X2: 4.5, Y2: 1.5}, 0, 0.5, false, geom.Vector2{}, geom.Vector2{}},
+		{"upper wall", geom.Line{X1: 1.5, Y1: 1.5, X2: 4.5, Y2: 1.5}, 1.2, 1.5,
+			true, geom.Vector2{X: 2.9, Y: 1.5}, geom.Vector2{X: -1}},
+		{"above top level", geom.Line{X1: 1.5, Y1: 1.5, X2: 4.5, Y2: 1.5}, 5, 6,
+			true, geom.Vector2{X: 2.9, Y: 1.5}, geom.Vector2{X: -1}},
+		{"leaving wall", geom.Line{X1: 1.95, Y1: 2.5, X2: 1.5, Y2: 2.5}, 0, 0.5,
+			true, geom.Vector2{X: 1.9, Y: 2.5}, geom.Vector2{X: 1}},
+		{"map border", geom.Line{X1: 0.5, Y1: 0.5, X2: 0.5, Y2: -0.5}, 0, 0.5,
+			true, geom.Vector2{X: 0.5, Y: 0.1}, geom.Vector2{Y: 1}},
+		{"diagonal", geom.Line{X1: 0.5, Y1: 0.5, X2: 3.5, Y2: 3.5}, 0, 0.5,
+			true, geom.Vector2{X: 1.9, Y: 1.9}, geom.Vector2{X: -1}},
+	}
+	near := func(a, b geom.Vector2) bool {
+		return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit, ok := m.WallHit(tt.line, tt.minZ, tt.maxZ, 0.1)
+			if ok != tt.hit {
+				t.Fatalf("got hit %v at %+v, want %v", ok, hit.Point, tt.hit)
+			}
+			if ok && (!near(hit.Point, tt.point) || !near(hit.Normal, tt.normal)) {
+				t.Errorf("got hit at %+v normal %+v, want %+v normal %+v", hit.Point, hit.Normal, tt.point, tt.normal)
+			}
+		})
+	}
+}
+
+func TestWallHit3d(t *testing.T) {
+	// (2, 2) is a wall on the lower level, (3, 1) on the upper one
+	m := &Map{width: 5, height: 5, levels: [][][]int{grid([2]int{2, 2}), grid([2]int{3, 1})}}
+
+	tests := []struct {
+		name   string
+		line   geom3d.Line3d
+		hit    bool
+		point  geom3d.Vector3
+		normal geom3d.Vector3
+	}{
+		{"over lower wall", geom3d.Line3d{X1: 0.5, Y1: 2.5, Z1: 1.5, X2: 4.5, Y2: 2.5, Z2: 1.5}, false, geom3d.Vector3{}, geom3d.Vector3{}},
+		{"falling onto lower wall", geom3d.Line3d{X1: 0.5, Y1: 2.5, Z1: 1.5, X2: 2.5, Y2: 2.5, Z2: 0.5},
+			true, geom3d.Vector3{X: 1.9, Y: 2.5, Z: 0.8}, geom3d.Vector3{X: -1}},
+		{"under upper wall", geom3d.Line3d{X1: 1.5, Y1: 1.5, Z1: 0.2, X2: 4.5, Y2: 1.5, Z2: 0.2}, false, geom3d.Vector3{}, geom3d.Vector3{}},
+		{"rising into upper wall", geom3d.Line3d{X1: 1.5, Y1: 1.5, Z1: 0.2, X2: 4.5, Y2: 1.5, Z2: 1.6},
+			true, geom3d.Vector3{X: 3, Y: 1.5, Z: 0.9}, geom3d.Vector3{Z: -1}},
+		{"map border", geom3d.Line3d{X1: 0.5, Y1: 0.5, Z1: 5, X2: 0.5, Y2: -0.5, Z2: 5},
+			true, geom3d.Vector3{X: 0.5, Y: 0.1, Z: 5}, geom3d.Vector3{Y: 1}},
+	}
+	near := func(a, b geom3d.Vector3) bool {
+		return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9 && math.Abs(a.Z-b.Z) < 1e-9
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit, ok := m.WallHit3d(tt.line, 0.1, 0.1, 0.1)
+			if ok != tt.hit {
+				t.Fatalf("got hit %v at %+v, want %v", ok, hit.Point, tt.hit)
+			}
+			if ok && (!near(hit.Point, tt.point) || !near(hit.Normal, tt.normal)) {
+				t.Errorf("got hit at %+v normal %+v, want %+v normal %+v", hit.Point, hit.Normal, tt.point, tt.normal)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultLevel is the level file in the levels directory of Assets.
+const DefaultLevel = "demo.json"
+
+// levelFile is the on-disk format of a level.
+//
+// Levels are listed from the ground floor up, each one is a grid of
+// width rows by height columns (indexed [x][y]). A cell value of 0 is empty,
+// any other value is the id of a wall listed in the asset manifest.
+// The optional Floor and Ceiling grids have the same shape as a level and
+// hold floor and ceiling ids of the manifest, 0 is the default floor or open sky.
+type levelFile struct {
+	Name    string      `json:"name"`
+	Width   int         `json:"width"`
+	Height  int         `json:"height"`
+	Player  PlayerStart `json:"player"`
+	Levels  [][][]int   `json:"levels"`
+	Floor   [][]int     `json:"floor"`
+	Ceiling [][]int     `json:"ceiling"`
+	Spawns  []Spawn     `json:"spawns"`
+}
+
+// ReadMap decodes and validates a level from r.
+func ReadMap(r io.Reader) (*Map, error) {
+	var lf levelFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&lf); err != nil {
+		return nil, fmt.Errorf("decode level: %w", err)
+	}
+	return lf.newMap()
+}
+
+// LoadMapFile loads a level from a file on disk, so levels can be changed
+// without rebuilding the game. Tiled maps (.tmx, .tmj) are imported with ReadTiledMap.
+func LoadMapFile(fname string) (*Map, error) {
+	switch filepath.Ext(fname) {
+	case ".tmx", ".tmj":
+		return loadTiledMapFile(fname)
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadMap(f)
+}
+
+// loadTiledMapFile imports a Tiled map from disk. Tiled refers to tilesets in
+// sibling directories like ../tilesets, so the map is read from the closest
+// directory holding the map and its tilesets, nothing above it can be opened.
+func loadTiledMapFile(fname string) (*Map, error) {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	tm, err := decodeTiledMap(abs, data)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Dir(abs)
+	for _, ts := range tm.tilesets {
+		if ts.source != "" {
+			source := filepath.Join(filepath.Dir(abs), filepath.FromSlash(ts.source))
+			root = commonDir(root, filepath.Dir(source))
+		}
+	}
+	name, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ReadTiledMap(os.DirFS(root), filepath.ToSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	for i, ts := range m.tilesets {
+		m.tilesets[i] = filepath.Join(root, filepath.FromSlash(ts))
+	}
+	return m, nil
+}
+
+// commonDir returns the closest directory holding the clean absolute directories a and b.
+func commonDir(a, b string) string {
+	for {
+		rel, err := filepath.Rel(a, b)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+}
+
+// LoadAssetMap loads a level from the levels directory of Assets.
+func LoadAssetMap(name string) (*Map, error) {
+	switch path.Ext(name) {
+	case ".tmx", ".tmj":
+		return ReadTiledMap(Assets, "levels/"+name)
+	}
+	f, err := Assets.Open("levels/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadMap(f)
+}
+
+func (lf *levelFile) newMap() (*Map, error) {
+	if err := lf.validate(); err != nil {
+		return nil, fmt.Errorf("level %q: %w", lf.Name, err)
+	}
+
+	m := &Map{
+		name:        lf.Name,
+		width:       lf.Width,
+		height:      lf.Height,
+		levels:      lf.Levels,
+		floor:       lf.Floor,
+		ceiling:     lf.Ceiling,
+		playerStart: lf.Player,
+		spawns:      lf.Spawns,
+	}
+	return m, nil
+}
+
+func (lf *levelFile) validate() error {
+	if lf.Width <= 0 || lf.Height <= 0 {
+		return fmt.Errorf("invalid size %vx%v", lf.Width, lf.Height)
+	}
+	if len(lf.Levels) == 0 {
+		return fmt.Errorf("no levels")
+	}
+
+	for l, level := range lf.Levels {
+		if err := lf.validateGrid(fmt.Sprintf("level %v", l), level); err != nil {
+			return err
+		}
+	}
+	if lf.Floor != nil {
+		if err := lf.validateGrid("floor", lf.Floor); err != nil {
+			return err
+		}
+	}
+	if lf.Ceiling != nil {
+		if err := lf.validateGrid("ceiling", lf.Ceiling); err != nil {
+			return err
+		}
+	}
+
+	px, py := int(lf.Player.X), int(lf.Player.Y)
+	if lf.Player.X < 0 || lf.Player.Y < 0 || px >= lf.Width || py >= lf.Height {
+		return fmt.Errorf("player start (%v, %v) is outside the map", lf.Player.X, lf.Player.Y)
+	}
+	if lf.Levels[0][px][py] != 0 {
+		return fmt.Errorf("player start (%v, %v) is inside a wall", lf.Player.X, lf.Player.Y)
+	}
+
+	for i, s := range lf.Spawns {
+		if s.Name == "" {
+			return fmt.Errorf("spawn %v has no name", i)
+		}
+		if s.X < 0 || s.Y < 0 || s.X >= float64(lf.Width) || s.Y >= float64(lf.Height) {
+			return fmt.Errorf("spawn %v (%v) at (%v, %v) is outside the map", i, s.Name, s.X, s.Y)
+		}
+	}
+	return nil
+}
+
+func (lf *levelFile) validateGrid(name string, grid [][]int) error {
+	if len(grid) != lf.Width {
+		return fmt.Errorf("%v has %v rows, want %v", name, len(grid), lf.Width)
+	}
+	for x, row := range grid {
+		if len(row) != lf.Height {
+			return fmt.Errorf("%v row %v has %v cells, want %v", name, x, len(row), lf.Height)
+		}
+		for y, id := range row {
+			if id < 0 {
+				return fmt.Errorf("%v cell (%v, %v) has invalid id %v", name, x, y, id)
+			}
+		}
+	}
+	return nil
+}
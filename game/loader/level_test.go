@@ -0,0 +1,83 @@
+package loader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadAssetMap(t *testing.T) {
+	m, err := LoadAssetMap(DefaultLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.NumLevels() != 4 || m.Width() != 24 || m.Height() != 24 {
+		t.Errorf("got %v levels of %vx%v", m.NumLevels(), m.Width(), m.Height())
+	}
+	if len(m.Level(0)) != m.Width() || len(m.Level(0)[0]) != m.Height() {
+		t.Errorf("level 0 does not match map size")
+	}
+	if !m.HasCeiling() {
+		t.Errorf("demo level has no ceiling")
+	}
+}
+
+func TestReadMap(t *testing.T) {
+	tests := []struct {
+		name  string
+		level string
+		err   string
+	}{
+		{
+			name:  "ok",
+			level: `{"width": 2, "height": 3, "player": {"x": 1.5, "y": 0.5}, "levels": [[[0, 1, 1], [0, 0, 1]]]}`,
+		},
+		{
+			name:  "no levels",
+			level: `{"width": 2, "height": 3, "levels": []}`,
+			err:   "no levels",
+		},
+		{
+			name:  "bad row",
+			level: `{"width": 2, "height": 3, "levels": [[[0, 0, 0], [0, 0]]]}`,
+			err:   "level 0 row 1 has 2 cells, want 3",
+		},
+		{
+			name:  "negative id",
+			level: `{"width": 1, "height": 2, "levels": [[[0, -1]]]}`,
+			err:   "cell (0, 1) has invalid id -1",
+		},
+		{
+			name:  "floor and ceiling",
+			level: `{"width": 1, "height": 2, "levels": [[[0, 0]]], "floor": [[1, 2]], "ceiling": [[0, 1]]}`,
+		},
+		{
+			name:  "bad ceiling",
+			level: `{"width": 1, "height": 2, "levels": [[[0, 0]]], "ceiling": [[1]]}`,
+			err:   "ceiling row 0 has 1 cells, want 2",
+		},
+		{
+			name:  "player in wall",
+			level: `{"width": 1, "height": 2, "player": {"x": 0.5, "y": 1.5}, "levels": [[[0, 1]]]}`,
+			err:   "inside a wall",
+		},
+		{
+			name:  "player outside",
+			level: `{"width": 1, "height": 2, "player": {"x": 4, "y": 0}, "levels": [[[0, 0]]]}`,
+			err:   "outside the map",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadMap(strings.NewReader(tt.level))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
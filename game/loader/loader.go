@@ -2,10 +2,9 @@ package loader
 
 import (
 	"embed"
+	"fmt"
 	"image"
-	"image/color"
 	"io/fs"
-	"log"
 	"path/filepath"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -25,86 +24,91 @@ const (
 	ClipDistance = 0.1
 )
 
-// loadContent will be called once per game and is the place to load
-// all of your content.
-func LoadContent(mapObj *Map) *TextureHandler {
-
-	// TODO: make resource management better
-	tex := NewTextureHandler(mapObj, 32)
-	// load wall textures
-	tex.Textures[0] = GetTextureFromFile("stone.png")
-	tex.Textures[1] = GetTextureFromFile("left_bot_house.png")
-	tex.Textures[2] = GetTextureFromFile("right_bot_house.png")
-	tex.Textures[3] = GetTextureFromFile("left_top_house.png")
-	tex.Textures[4] = GetTextureFromFile("right_top_house.png")
-	tex.Textures[5] = GetTextureFromFile("ebitengine_splash.png")
-
-	// separating sprites out a bit from wall textures
-	tex.Textures[8] = GetSpriteFromFile("large_rock.png")
-	tex.Textures[9] = GetSpriteFromFile("tree_09.png")
-	tex.Textures[10] = GetSpriteFromFile("tree_10.png")
-	tex.Textures[14] = GetSpriteFromFile("tree_14.png")
-
-	// load texture sheets
-	tex.Textures[15] = GetSpriteFromFile("sorcerer_sheet.png")
-	tex.Textures[16] = GetSpriteFromFile("crosshairs_sheet.png")
-	tex.Textures[17] = GetSpriteFromFile("charged_bolt_sheet.png")
-	tex.Textures[18] = GetSpriteFromFile("blue_explosion_sheet.png")
-	tex.Textures[19] = GetSpriteFromFile("outleader_walking_sheet.png")
-	tex.Textures[20] = GetSpriteFromFile("hand_spell.png")
-	tex.Textures[21] = GetSpriteFromFile("hand_staff.png")
-	tex.Textures[22] = GetSpriteFromFile("red_bolt.png")
-	tex.Textures[23] = GetSpriteFromFile("red_explosion_sheet.png")
-	tex.Textures[24] = GetSpriteFromFile("bat_sheet.png")
-
-	// just setting the grass texture apart from the rest since it gets special handling
-	return tex
-}
+// LoadContent will be called once per level and is the place to load
+// all of the wall, floor and ceiling textures used by the level.
+func LoadContent(mapObj *Map, manifest *Manifest) (*TextureHandler, error) {
+	tex := NewTextureHandler(mapObj)
+
+	loaded := map[string]*ebiten.Image{}
+	for l := 0; l < mapObj.NumLevels(); l++ {
+		for _, row := range mapObj.Level(l) {
+			for _, id := range row {
+				if _, ok := tex.walls[id]; id == 0 || ok {
+					continue
+				}
+				wall, ok := manifest.Walls[id]
+				if !ok {
+					return nil, fmt.Errorf("level %q uses wall id %v which is not in the manifest", mapObj.Name(), id)
+				}
+				var sides [2]*ebiten.Image
+				for i, name := range wall.sides() {
+					if loaded[name] == nil {
+						img, err := manifest.Texture(name)
+						if err != nil {
+							return nil, err
+						}
+						loaded[name] = img
+					}
+					sides[i] = loaded[name]
+				}
+				tex.walls[id] = sides
+			}
+		}
+	}
 
-func NewImageFromFile(path string) (*ebiten.Image, image.Image, error) {
-	f, err := Embedded.Open(filepath.ToSlash(path))
-	if err != nil {
-		return nil, nil, err
+	for x := 0; x < mapObj.Width(); x++ {
+		for y := 0; y < mapObj.Height(); y++ {
+			if err := loadFlat(tex.floors, manifest.Floors, "floor", mapObj.FloorAt(x, y), manifest); err != nil {
+				return nil, fmt.Errorf("level %q: %w", mapObj.Name(), err)
+			}
+			if err := loadFlat(tex.ceilings, manifest.Ceilings, "ceiling", mapObj.CeilingAt(x, y), manifest); err != nil {
+				return nil, fmt.Errorf("level %q: %w", mapObj.Name(), err)
+			}
+		}
 	}
-	defer f.Close()
-	eb, im, err := ebitenutil.NewImageFromReader(f)
-	return eb, im, err
+
+	return tex, nil
 }
 
-func GetRGBAFromFile(texFile string) *image.RGBA {
-	var rgba *image.RGBA
-	_, tex, err := NewImageFromFile("resources/textures/" + texFile)
-	if err != nil {
-		log.Fatal(err)
+// loadFlat loads the floor or ceiling texture of id into loaded.
+func loadFlat(loaded map[int]*image.RGBA, names map[int]string, kind string, id int, manifest *Manifest) error {
+	if _, ok := loaded[id]; id == 0 || ok {
+		return nil
 	}
-	if tex != nil {
-		rgba = image.NewRGBA(image.Rect(0, 0, TexWidth, TexWidth))
-		// convert into RGBA format
-		for x := 0; x < TexWidth; x++ {
-			for y := 0; y < TexWidth; y++ {
-				clr := tex.At(x, y).(color.RGBA)
-				rgba.SetRGBA(x, y, clr)
-			}
-		}
+	name, ok := names[id]
+	if !ok {
+		return fmt.Errorf("%v id %v is not in the manifest", kind, id)
 	}
+	rgba, err := manifest.TextureRGBA(name)
+	if err != nil {
+		return err
+	}
+	loaded[id] = rgba
+	return nil
+}
 
-	return rgba
+// NewImageFromFile loads the image path of Assets.
+func NewImageFromFile(path string) (*ebiten.Image, image.Image, error) {
+	return newImageFromFS(Assets, path)
 }
 
-func GetTextureFromFile(texFile string) *ebiten.Image {
-	eImg, _, err := NewImageFromFile("resources/textures/" + texFile)
+func newImageFromFS(fsys fs.FS, path string) (*ebiten.Image, image.Image, error) {
+	f, err := fsys.Open(filepath.ToSlash(path))
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
-	return eImg
+	defer f.Close()
+	eb, im, err := ebitenutil.NewImageFromReader(f)
+	return eb, im, err
 }
 
-func GetSpriteFromFile(sFile string) *ebiten.Image {
-	eImg, _, err := NewImageFromFile("resources/sprites/" + sFile)
+// GetSprite loads the sprite sheet name of the default manifest.
+func GetSprite(name string) (SpriteSheet, error) {
+	m, err := DefaultManifest()
 	if err != nil {
-		log.Fatal(err)
+		return SpriteSheet{}, err
 	}
-	return eImg
+	return m.Sprite(name)
 }
 
 // Todo
@@ -341,10 +345,7 @@ func GetSpriteFromFile(sFile string) *ebiten.Image {
 // 	delete(g.effects, effect)
 // }
 
-func LoadAudioFile(fname string) fs.File {
-	f, err := Embedded.Open("resources/audio/" + fname)
-	if err != nil {
-		log.Fatalf("Load audio file fail: %e", err)
-	}
-	return f
+// LoadAudioFile opens the audio file fname of Assets.
+func LoadAudioFile(fname string) (fs.File, error) {
+	return Assets.Open("audio/" + fname)
 }
@@ -0,0 +1,225 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"path"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ManifestFile is the asset manifest in resources.
+const ManifestFile = "manifest.json"
+
+// Manifest describes the assets of the game by name, so adding a texture,
+// sprite or wall type is a change to the manifest only.
+// File names are relative to the directory of the manifest.
+type Manifest struct {
+	fsys fs.FS
+	// Textures maps a texture name to its file
+	Textures map[string]string `json:"textures"`
+	// Sprites maps a sprite name to its sprite sheet
+	Sprites map[string]SpriteInfo `json:"sprites"`
+	// Walls maps the cell id used in levels to its wall
+	Walls map[int]Wall `json:"walls"`
+	// Floors and Ceilings map the ids used in the floor and ceiling
+	// layers of levels to their texture
+	Floors   map[int]string `json:"floors"`
+	Ceilings map[int]string `json:"ceilings"`
+	// minimap colour of empty cells and of walls without their own colour
+	EmptyColor Color `json:"emptyColor"`
+	WallColor  Color `json:"wallColor"`
+}
+
+// SpriteInfo is the file of a sprite sheet and its grid of frames.
+type SpriteInfo struct {
+	File    string `json:"file"`
+	Columns int    `json:"columns"`
+	Rows    int    `json:"rows"`
+}
+
+// SpriteSheet is a loaded sprite sheet.
+type SpriteSheet struct {
+	Img     *ebiten.Image
+	Columns int
+	Rows    int
+}
+
+// Wall is a cell type of the levels.
+// SideX is the texture of the faces hit by rays stepping along X (raycaster side 0),
+// SideY the one of faces hit stepping along Y (side 1). Both default to Texture.
+type Wall struct {
+	Texture string `json:"texture"`
+	SideX   string `json:"sideX"`
+	SideY   string `json:"sideY"`
+	Color   *Color `json:"color"`
+}
+
+// Color is a colour written as "#rrggbb" or "#rrggbbaa".
+type Color color.RGBA
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	var n int
+	var err error
+	c.A = 255
+	switch len(s) {
+	case 7:
+		n, err = fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 9:
+		n, err = fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	}
+	if err != nil || n < 3 {
+		return fmt.Errorf("invalid colour %q", s)
+	}
+	return nil
+}
+
+// LoadManifest reads the manifest name from fsys,
+// the assets it lists are loaded from the same directory.
+func LoadManifest(fsys fs.FS, name string) (*Manifest, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("manifest %v: %w", name, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("manifest %v: %w", name, err)
+	}
+	if m.fsys, err = fs.Sub(fsys, path.Dir(name)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var defaultManifest struct {
+	once     sync.Once
+	manifest *Manifest
+	err      error
+}
+
+// DefaultManifest returns the manifest of Assets, it is loaded on first use.
+func DefaultManifest() (*Manifest, error) {
+	defaultManifest.once.Do(func() {
+		defaultManifest.manifest, defaultManifest.err = LoadManifest(Assets, ManifestFile)
+	})
+	return defaultManifest.manifest, defaultManifest.err
+}
+
+func (m *Manifest) validate() error {
+	for name, file := range m.Textures {
+		if file == "" {
+			return fmt.Errorf("texture %q has no file", name)
+		}
+	}
+	for name, s := range m.Sprites {
+		if s.File == "" {
+			return fmt.Errorf("sprite %q has no file", name)
+		}
+		if s.Columns <= 0 || s.Rows <= 0 {
+			return fmt.Errorf("sprite %q has invalid grid %vx%v", name, s.Columns, s.Rows)
+		}
+	}
+	for id, w := range m.Walls {
+		if id <= 0 {
+			return fmt.Errorf("wall id %v must be greater than 0", id)
+		}
+		for _, tex := range []string{w.Texture, w.SideX, w.SideY} {
+			if _, ok := m.Textures[tex]; tex != "" && !ok {
+				return fmt.Errorf("wall %v uses unknown texture %q", id, tex)
+			}
+		}
+		if w.Texture == "" && (w.SideX == "" || w.SideY == "") {
+			return fmt.Errorf("wall %v has no texture", id)
+		}
+	}
+	for kind, flats := range map[string]map[int]string{"floor": m.Floors, "ceiling": m.Ceilings} {
+		for id, tex := range flats {
+			if id <= 0 {
+				return fmt.Errorf("%v id %v must be greater than 0", kind, id)
+			}
+			if _, ok := m.Textures[tex]; !ok {
+				return fmt.Errorf("%v %v uses unknown texture %q", kind, id, tex)
+			}
+		}
+	}
+	return nil
+}
+
+// sides returns the texture names of the wall for raycaster side 0 and 1.
+func (w *Wall) sides() [2]string {
+	sides := [2]string{w.SideX, w.SideY}
+	for i, s := range sides {
+		if s == "" {
+			sides[i] = w.Texture
+		}
+	}
+	return sides
+}
+
+// MapColor returns the minimap colour of the cell id.
+func (m *Manifest) MapColor(id int) color.RGBA {
+	if id == 0 {
+		return color.RGBA(m.EmptyColor)
+	}
+	if w, ok := m.Walls[id]; ok && w.Color != nil {
+		return color.RGBA(*w.Color)
+	}
+	return color.RGBA(m.WallColor)
+}
+
+// Texture loads the texture name.
+func (m *Manifest) Texture(name string) (*ebiten.Image, error) {
+	file, ok := m.Textures[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown texture %q", name)
+	}
+	eImg, _, err := newImageFromFS(m.fsys, file)
+	return eImg, err
+}
+
+// TextureRGBA loads the texture name as TexWidth x TexWidth RGBA image,
+// the format used for floor textures.
+func (m *Manifest) TextureRGBA(name string) (*image.RGBA, error) {
+	file, ok := m.Textures[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown texture %q", name)
+	}
+	_, tex, err := newImageFromFS(m.fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, TexWidth, TexWidth))
+	// convert into RGBA format, stretching textures of other sizes
+	b := tex.Bounds()
+	for x := 0; x < TexWidth; x++ {
+		for y := 0; y < TexWidth; y++ {
+			clr := color.RGBAModel.Convert(tex.At(b.Min.X+x*b.Dx()/TexWidth, b.Min.Y+y*b.Dy()/TexWidth)).(color.RGBA)
+			rgba.SetRGBA(x, y, clr)
+		}
+	}
+	return rgba, nil
+}
+
+// Sprite loads the sprite sheet name.
+func (m *Manifest) Sprite(name string) (SpriteSheet, error) {
+	info, ok := m.Sprites[name]
+	if !ok {
+		return SpriteSheet{}, fmt.Errorf("unknown sprite %q", name)
+	}
+	eImg, _, err := newImageFromFS(m.fsys, info.File)
+	if err != nil {
+		return SpriteSheet{}, err
+	}
+	return SpriteSheet{Img: eImg, Columns: info.Columns, Rows: info.Rows}, nil
+}
@@ -7,24 +7,29 @@ import (
 )
 
 type TextureHandler struct {
-	mapObj         *Map
-	Textures       []*ebiten.Image
+	mapObj *Map
+	// wall textures of each cell id for raycaster side 0 and 1
+	walls map[int][2]*ebiten.Image
+	// textures of the floor and ceiling ids of the map
+	floors   map[int]*image.RGBA
+	ceilings map[int]*image.RGBA
+	// FloorTex is the floor of cells without a floor id
 	FloorTex       *image.RGBA
 	RenderFloorTex bool
 }
 
-func NewTextureHandler(mapObj *Map, textureCapacity int) *TextureHandler {
+func NewTextureHandler(mapObj *Map) *TextureHandler {
 	t := &TextureHandler{
 		mapObj:         mapObj,
-		Textures:       make([]*ebiten.Image, textureCapacity),
+		walls:          map[int][2]*ebiten.Image{},
+		floors:         map[int]*image.RGBA{},
+		ceilings:       map[int]*image.RGBA{},
 		RenderFloorTex: true,
 	}
 	return t
 }
 
 func (t *TextureHandler) TextureAt(x, y, levelNum, side int) *ebiten.Image {
-	texNum := -1
-
 	mapLevel := t.mapObj.Level(levelNum)
 	if mapLevel == nil {
 		return nil
@@ -39,42 +44,28 @@ func (t *TextureHandler) TextureAt(x, y, levelNum, side int) *ebiten.Image {
 		return nil
 	}
 
-	if x >= 0 && x < mapWidth && y >= 0 && y < mapHeight {
-		texNum = mapLevel[x][y] - 1 // 1 subtracted from it so that texture 0 can be used
-	}
-
-	if side == 0 {
-		//--some supid hacks to make the houses render correctly--//
-		// this corrects textures on two sides of house since the textures are not symmetrical
-		if texNum == 3 {
-			texNum = 4
-		} else if texNum == 4 {
-			texNum = 3
-		}
-
-		if texNum == 1 {
-			texNum = 4
-		} else if texNum == 2 {
-			texNum = 3
-		}
-
-		// make the ebitengine splash only show on one side
-		if texNum == 5 {
-			texNum = 0
-		}
+	if x < 0 || x >= mapWidth || y < 0 || y >= mapHeight {
+		return nil
 	}
 
-	if texNum < 0 {
+	sides, ok := t.walls[mapLevel[x][y]]
+	if !ok || side < 0 || side > 1 {
 		return nil
 	}
-	return t.Textures[texNum]
+	return sides[side]
 }
 
 func (t *TextureHandler) FloorTextureAt(x, y int) *image.RGBA {
-	// x/y could be used to render different floor texture at given coords,
-	// but for this demo we will just be rendering the same texture everywhere.
-	if t.RenderFloorTex {
-		return t.FloorTex
+	if !t.RenderFloorTex {
+		return nil
+	}
+	if tex, ok := t.floors[t.mapObj.FloorAt(x, y)]; ok {
+		return tex
 	}
-	return nil
+	return t.FloorTex
+}
+
+// CeilingTextureAt returns the ceiling texture of the cell, nil for open sky.
+func (t *TextureHandler) CeilingTextureAt(x, y int) *image.RGBA {
+	return t.ceilings[t.mapObj.CeilingAt(x, y)]
 }
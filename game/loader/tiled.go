@@ -0,0 +1,642 @@
+package loader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Importer for maps made with the Tiled editor (https://www.mapeditor.org).
+//
+//   - Every tile layer becomes a level, in the order they are listed in Tiled
+//     (lowest layer is the ground floor). Groups are flattened.
+//   - Tile layers named "floor" and "ceiling" are the floor and ceiling of
+//     the level instead.
+//   - A cell id is the "wall" property of the tile (or "floor", "ceiling" on
+//     those layers), the id in the asset manifest, or else the global tile
+//     id (gid) of the tile.
+//   - Objects become spawn points. The object type (class) is the kind and
+//     its name says which actor to create. The object of type "player" is the
+//     player start. Optional properties "angle" (degrees) and "z" are read.
+//
+// Tiled column is the map X and Tiled row is the map Y.
+
+const (
+	tiledPlayerKind = "player"
+
+	tiledWall    = "wall"
+	tiledFloor   = "floor"
+	tiledCeiling = "ceiling"
+
+	// Tiled stores tile flipping in the high bits of a gid
+	tiledGIDMask = 0x0fffffff
+)
+
+// tiledMap is the part of a Tiled map used by the importer,
+// decoded from either TMX or TMJ.
+type tiledMap struct {
+	orientation string
+	infinite    bool
+	width       int
+	height      int
+	tileWidth   float64
+	tileHeight  float64
+	properties  map[string]string
+	tilesets    []tiledTileset
+	// tile layers and object layers in document order, groups flattened
+	layers []tiledLayer
+}
+
+type tiledTileset struct {
+	firstGID int
+	source   string
+	tiles    []tiledTile
+}
+
+type tiledTile struct {
+	id         int
+	properties map[string]string
+}
+
+type tiledLayer struct {
+	name    string
+	isTiles bool
+	gids    []uint32
+	objects []tiledObject
+}
+
+type tiledObject struct {
+	name       string
+	kind       string
+	x, y       float64
+	w, h       float64
+	gid        uint32
+	point      bool
+	properties map[string]string
+}
+
+// ReadTiledMap imports the Tiled map name (.tmx or .tmj) from fsys.
+// External tilesets are read relative to the map, they must be in fsys too.
+func ReadTiledMap(fsys fs.FS, name string) (*Map, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tm, err := decodeTiledMap(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []string
+	for i, ts := range tm.tilesets {
+		if ts.source == "" {
+			continue
+		}
+		source := path.Join(path.Dir(name), ts.source)
+		if !fs.ValidPath(source) {
+			return nil, fmt.Errorf("import %v: tileset %v is outside of the map directory", name, ts.source)
+		}
+		tiles, err := readTiledTileset(fsys, source)
+		if err != nil {
+			return nil, fmt.Errorf("import %v: %w", name, err)
+		}
+		tm.tilesets[i].tiles = tiles
+		sources = append(sources, source)
+	}
+
+	lf, err := tm.levelFile()
+	if err != nil {
+		return nil, fmt.Errorf("import %v: %w", name, err)
+	}
+	if lf.Name == "" {
+		lf.Name = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	m, err := lf.newMap()
+	if err != nil {
+		return nil, err
+	}
+	m.tilesets = sources
+	return m, nil
+}
+
+// decodeTiledMap decodes the Tiled map name from data by its extension.
+func decodeTiledMap(name string, data []byte) (tm *tiledMap, err error) {
+	switch path.Ext(name) {
+	case ".tmx":
+		tm, err = decodeTMX(data)
+	case ".tmj", ".json":
+		tm, err = decodeTMJ(data)
+	default:
+		err = fmt.Errorf("unknown Tiled map format %q", path.Ext(name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import %v: %w", name, err)
+	}
+	return tm, nil
+}
+
+func (tm *tiledMap) levelFile() (*levelFile, error) {
+	if tm.orientation != "orthogonal" {
+		return nil, fmt.Errorf("orientation %q is not supported", tm.orientation)
+	}
+	if tm.infinite {
+		return nil, fmt.Errorf("infinite maps are not supported")
+	}
+	if tm.tileWidth <= 0 || tm.tileHeight <= 0 {
+		return nil, fmt.Errorf("invalid tile size %vx%v", tm.tileWidth, tm.tileHeight)
+	}
+
+	lf := &levelFile{
+		Name:   tm.properties["name"],
+		Width:  tm.width,
+		Height: tm.height,
+	}
+
+	// ids of tiles with a "wall", "floor" or "ceiling" property
+	ids := map[string]map[int]int{}
+	for _, prop := range []string{tiledWall, tiledFloor, tiledCeiling} {
+		ids[prop] = map[int]int{}
+		for _, ts := range tm.tilesets {
+			for _, t := range ts.tiles {
+				v, ok := t.properties[prop]
+				if !ok {
+					continue
+				}
+				id, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, fmt.Errorf("tile %v: property %q: %w", ts.firstGID+t.id, prop, err)
+				}
+				ids[prop][ts.firstGID+t.id] = id
+			}
+		}
+	}
+
+	hasPlayer := false
+	for _, l := range tm.layers {
+		if l.isTiles {
+			if len(l.gids) != tm.width*tm.height {
+				return nil, fmt.Errorf("layer %q has %v tiles, want %v", l.name, len(l.gids), tm.width*tm.height)
+			}
+			prop := tiledWall
+			if l.name == tiledFloor || l.name == tiledCeiling {
+				prop = l.name
+			}
+			grid := make([][]int, tm.width)
+			for x := range grid {
+				grid[x] = make([]int, tm.height)
+				for y := range grid[x] {
+					id := int(l.gids[y*tm.width+x] & tiledGIDMask)
+					if mapped, ok := ids[prop][id]; ok {
+						id = mapped
+					}
+					grid[x][y] = id
+				}
+			}
+			switch {
+			case prop == tiledFloor && lf.Floor == nil:
+				lf.Floor = grid
+			case prop == tiledCeiling && lf.Ceiling == nil:
+				lf.Ceiling = grid
+			case prop != tiledWall:
+				return nil, fmt.Errorf("more than one %q layer", prop)
+			default:
+				lf.Levels = append(lf.Levels, grid)
+			}
+			continue
+		}
+
+		for _, o := range l.objects {
+			x, y := o.center()
+			x, y = x/tm.tileWidth, y/tm.tileHeight
+			angle, err := tiledFloatProperty(o.properties, "angle")
+			if err != nil {
+				return nil, fmt.Errorf("object %q: %w", o.name, err)
+			}
+			if o.kind == tiledPlayerKind {
+				if hasPlayer {
+					return nil, fmt.Errorf("more than one %q object", tiledPlayerKind)
+				}
+				hasPlayer = true
+				lf.Player = PlayerStart{X: x, Y: y, Angle: angle}
+				continue
+			}
+			z, err := tiledFloatProperty(o.properties, "z")
+			if err != nil {
+				return nil, fmt.Errorf("object %q: %w", o.name, err)
+			}
+			lf.Spawns = append(lf.Spawns, Spawn{
+				Kind:       o.kind,
+				Name:       o.name,
+				X:          x,
+				Y:          y,
+				Z:          z,
+				Angle:      angle,
+				Properties: o.properties,
+			})
+		}
+	}
+	if !hasPlayer {
+		return nil, fmt.Errorf("no %q object", tiledPlayerKind)
+	}
+
+	return lf, nil
+}
+
+// center returns the point of the object used as spawn position in pixels.
+func (o *tiledObject) center() (float64, float64) {
+	switch {
+	case o.point:
+		return o.x, o.y
+	case o.gid != 0:
+		// tile objects are aligned to their bottom left corner
+		return o.x + o.w/2, o.y - o.h/2
+	default:
+		return o.x + o.w/2, o.y + o.h/2
+	}
+}
+
+func tiledFloatProperty(props map[string]string, name string) (float64, error) {
+	v, ok := props[name]
+	if !ok || v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("property %q: %w", name, err)
+	}
+	return f, nil
+}
+
+func readTiledTileset(fsys fs.FS, name string) ([]tiledTile, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var ts tiledTileset
+	switch path.Ext(name) {
+	case ".tsx":
+		var x tmxTileset
+		if err := xml.Unmarshal(data, &x); err != nil {
+			return nil, fmt.Errorf("tileset %v: %w", name, err)
+		}
+		ts = x.tileset()
+	case ".tsj", ".json":
+		var j tmjTileset
+		if err := json.Unmarshal(data, &j); err != nil {
+			return nil, fmt.Errorf("tileset %v: %w", name, err)
+		}
+		ts = j.tileset()
+	default:
+		return nil, fmt.Errorf("unknown Tiled tileset format %q", path.Ext(name))
+	}
+	return ts.tiles, nil
+}
+
+// decodeTiledData decodes base64 layer data with optional compression
+// into a list of gids.
+func decodeTiledData(data string, compression string) ([]uint32, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = bytes.NewReader(raw)
+	switch compression {
+	case "":
+	case "zlib":
+		if r, err = zlib.NewReader(r); err != nil {
+			return nil, err
+		}
+	case "gzip":
+		if r, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("compression %q is not supported", compression)
+	}
+	raw, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw)%4 != 0 {
+		return nil, fmt.Errorf("layer data has %v bytes, not a multiple of 4", len(raw))
+	}
+
+	gids := make([]uint32, len(raw)/4)
+	for i := range gids {
+		b := raw[i*4:]
+		gids[i] = uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+	}
+	return gids, nil
+}
+
+// TMX (XML) format
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"`
+}
+
+func tmxProperties(props []tmxProperty) map[string]string {
+	m := make(map[string]string, len(props))
+	for _, p := range props {
+		if p.Value == "" {
+			// multi-line string properties are stored as text
+			m[p.Name] = p.Text
+		} else {
+			m[p.Name] = p.Value
+		}
+	}
+	return m
+}
+
+type tmxMap struct {
+	Orientation string        `xml:"orientation,attr"`
+	Infinite    int           `xml:"infinite,attr"`
+	Width       int           `xml:"width,attr"`
+	Height      int           `xml:"height,attr"`
+	TileWidth   float64       `xml:"tilewidth,attr"`
+	TileHeight  float64       `xml:"tileheight,attr"`
+	Properties  []tmxProperty `xml:"properties>property"`
+	Tilesets    []tmxTileset  `xml:"tileset"`
+	// layer, objectgroup and group elements, any other element is skipped
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxTileset struct {
+	FirstGID int    `xml:"firstgid,attr"`
+	Source   string `xml:"source,attr"`
+	Tiles    []struct {
+		ID         int           `xml:"id,attr"`
+		Properties []tmxProperty `xml:"properties>property"`
+	} `xml:"tile"`
+}
+
+func (x *tmxTileset) tileset() tiledTileset {
+	ts := tiledTileset{firstGID: x.FirstGID, source: x.Source}
+	for _, t := range x.Tiles {
+		ts.tiles = append(ts.tiles, tiledTile{
+			id: t.ID, properties: tmxProperties(t.Properties),
+		})
+	}
+	return ts
+}
+
+type tmxLayer struct {
+	XMLName    xml.Name
+	Name       string        `xml:"name,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+	Data       struct {
+		Encoding    string `xml:"encoding,attr"`
+		Compression string `xml:"compression,attr"`
+		Text        string `xml:",chardata"`
+		Tiles       []struct {
+			GID uint32 `xml:"gid,attr"`
+		} `xml:"tile"`
+	} `xml:"data"`
+	Objects []struct {
+		Name       string        `xml:"name,attr"`
+		Type       string        `xml:"type,attr"`
+		Class      string        `xml:"class,attr"`
+		X          float64       `xml:"x,attr"`
+		Y          float64       `xml:"y,attr"`
+		Width      float64       `xml:"width,attr"`
+		Height     float64       `xml:"height,attr"`
+		GID        uint32        `xml:"gid,attr"`
+		Point      *struct{}     `xml:"point"`
+		Properties []tmxProperty `xml:"properties>property"`
+	} `xml:"object"`
+	// children of a group
+	Layers []tmxLayer `xml:",any"`
+}
+
+func decodeTMX(data []byte) (*tiledMap, error) {
+	var x tmxMap
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+	tm := &tiledMap{
+		orientation: x.Orientation,
+		infinite:    x.Infinite != 0,
+		width:       x.Width,
+		height:      x.Height,
+		tileWidth:   x.TileWidth,
+		tileHeight:  x.TileHeight,
+		properties:  tmxProperties(x.Properties),
+	}
+	for i := range x.Tilesets {
+		tm.tilesets = append(tm.tilesets, x.Tilesets[i].tileset())
+	}
+	if err := tm.addTMXLayers(x.Layers); err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+func (tm *tiledMap) addTMXLayers(layers []tmxLayer) error {
+	for _, l := range layers {
+		switch l.XMLName.Local {
+		case "layer":
+			gids, err := l.gids()
+			if err != nil {
+				return fmt.Errorf("layer %q: %w", l.Name, err)
+			}
+			tm.layers = append(tm.layers, tiledLayer{name: l.Name, isTiles: true, gids: gids})
+		case "objectgroup":
+			tl := tiledLayer{name: l.Name}
+			for _, o := range l.Objects {
+				kind := o.Type
+				if kind == "" {
+					kind = o.Class
+				}
+				tl.objects = append(tl.objects, tiledObject{
+					name: o.Name, kind: kind,
+					x: o.X, y: o.Y, w: o.Width, h: o.Height,
+					gid: o.GID, point: o.Point != nil,
+					properties: tmxProperties(o.Properties),
+				})
+			}
+			tm.layers = append(tm.layers, tl)
+		case "group":
+			if err := tm.addTMXLayers(l.Layers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *tmxLayer) gids() ([]uint32, error) {
+	switch l.Data.Encoding {
+	case "":
+		gids := make([]uint32, len(l.Data.Tiles))
+		for i, t := range l.Data.Tiles {
+			gids[i] = t.GID
+		}
+		return gids, nil
+	case "csv":
+		fields := strings.Split(strings.TrimSpace(l.Data.Text), ",")
+		gids := make([]uint32, len(fields))
+		for i, f := range fields {
+			gid, err := strconv.ParseUint(strings.TrimSpace(f), 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			gids[i] = uint32(gid)
+		}
+		return gids, nil
+	case "base64":
+		return decodeTiledData(l.Data.Text, l.Data.Compression)
+	default:
+		return nil, fmt.Errorf("encoding %q is not supported", l.Data.Encoding)
+	}
+}
+
+// TMJ (JSON) format
+
+type tmjProperty struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+func tmjProperties(props []tmjProperty) map[string]string {
+	m := make(map[string]string, len(props))
+	for _, p := range props {
+		m[p.Name] = fmt.Sprint(p.Value)
+	}
+	return m
+}
+
+type tmjMap struct {
+	Orientation string        `json:"orientation"`
+	Infinite    bool          `json:"infinite"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	TileWidth   float64       `json:"tilewidth"`
+	TileHeight  float64       `json:"tileheight"`
+	Properties  []tmjProperty `json:"properties"`
+	Tilesets    []tmjTileset  `json:"tilesets"`
+	Layers      []tmjLayer    `json:"layers"`
+}
+
+type tmjTileset struct {
+	FirstGID int    `json:"firstgid"`
+	Source   string `json:"source"`
+	Tiles    []struct {
+		ID         int           `json:"id"`
+		Properties []tmjProperty `json:"properties"`
+	} `json:"tiles"`
+}
+
+func (j *tmjTileset) tileset() tiledTileset {
+	ts := tiledTileset{firstGID: j.FirstGID, source: j.Source}
+	for _, t := range j.Tiles {
+		ts.tiles = append(ts.tiles, tiledTile{
+			id: t.ID, properties: tmjProperties(t.Properties),
+		})
+	}
+	return ts
+}
+
+type tmjLayer struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Objects     []struct {
+		Name       string        `json:"name"`
+		Type       string        `json:"type"`
+		Class      string        `json:"class"`
+		X          float64       `json:"x"`
+		Y          float64       `json:"y"`
+		Width      float64       `json:"width"`
+		Height     float64       `json:"height"`
+		GID        uint32        `json:"gid"`
+		Point      bool          `json:"point"`
+		Properties []tmjProperty `json:"properties"`
+	} `json:"objects"`
+	Layers []tmjLayer `json:"layers"`
+}
+
+func decodeTMJ(data []byte) (*tiledMap, error) {
+	var j tmjMap
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	tm := &tiledMap{
+		orientation: j.Orientation,
+		infinite:    j.Infinite,
+		width:       j.Width,
+		height:      j.Height,
+		tileWidth:   j.TileWidth,
+		tileHeight:  j.TileHeight,
+		properties:  tmjProperties(j.Properties),
+	}
+	for i := range j.Tilesets {
+		tm.tilesets = append(tm.tilesets, j.Tilesets[i].tileset())
+	}
+	if err := tm.addTMJLayers(j.Layers); err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+func (tm *tiledMap) addTMJLayers(layers []tmjLayer) error {
+	for _, l := range layers {
+		switch l.Type {
+		case "tilelayer":
+			gids, err := l.gids()
+			if err != nil {
+				return fmt.Errorf("layer %q: %w", l.Name, err)
+			}
+			tm.layers = append(tm.layers, tiledLayer{name: l.Name, isTiles: true, gids: gids})
+		case "objectgroup":
+			tl := tiledLayer{name: l.Name}
+			for _, o := range l.Objects {
+				kind := o.Type
+				if kind == "" {
+					kind = o.Class
+				}
+				tl.objects = append(tl.objects, tiledObject{
+					name: o.Name, kind: kind,
+					x: o.X, y: o.Y, w: o.Width, h: o.Height,
+					gid: o.GID, point: o.Point,
+					properties: tmjProperties(o.Properties),
+				})
+			}
+			tm.layers = append(tm.layers, tl)
+		case "group":
+			if err := tm.addTMJLayers(l.Layers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *tmjLayer) gids() ([]uint32, error) {
+	if l.Encoding == "base64" {
+		var s string
+		if err := json.Unmarshal(l.Data, &s); err != nil {
+			return nil, err
+		}
+		return decodeTiledData(s, l.Compression)
+	}
+	var gids []uint32
+	if err := json.Unmarshal(l.Data, &gids); err != nil {
+		return nil, err
+	}
+	return gids, nil
+}
@@ -0,0 +1,194 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testTMX = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="3" height="2" tilewidth="32" tileheight="32" infinite="0">
+ <tileset firstgid="1" source="walls.tsx"/>
+ <layer id="1" name="ground" width="3" height="2">
+  <data encoding="csv">
+1,0,2,
+1,0,0
+</data>
+ </layer>
+ <layer id="5" name="floor" width="3" height="2">
+  <data encoding="csv">0,3,0,0,3,0</data>
+ </layer>
+ <group id="3" name="upper">
+  <layer id="2" name="mid" width="3" height="2">
+   <data encoding="csv">1,0,0,0,0,0</data>
+  </layer>
+ </group>
+ <objectgroup id="4" name="spawns">
+  <object id="1" name="player" type="player" x="48" y="16">
+   <properties>
+    <property name="angle" type="float" value="90"/>
+   </properties>
+   <point/>
+  </object>
+  <object id="2" name="bat" class="enemy" x="64" y="32" width="32" height="32">
+   <properties>
+    <property name="z" type="float" value="1.5"/>
+   </properties>
+  </object>
+ </objectgroup>
+</map>`
+
+const testTSX = `<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" name="walls" tilewidth="32" tileheight="32" tilecount="3" columns="0">
+ <tile id="0">
+  <image source="../textures/stone.png" width="32" height="32"/>
+ </tile>
+ <tile id="1">
+  <properties>
+   <property name="wall" type="int" value="6"/>
+  </properties>
+ </tile>
+ <tile id="2">
+  <properties>
+   <property name="floor" type="int" value="2"/>
+  </properties>
+ </tile>
+</tileset>`
+
+const testTMJ = `{
+ "orientation": "orthogonal", "width": 2, "height": 2, "tilewidth": 16, "tileheight": 16,
+ "properties": [{"name": "name", "type": "string", "value": "tmj"}],
+ "tilesets": [{"firstgid": 5, "tiles": [{"id": 0, "image": "stone.png"}]}],
+ "layers": [
+  {"type": "tilelayer", "name": "ground", "width": 2, "height": 2, "data": [5, 2147483653, 0, 0]},
+  {"type": "objectgroup", "name": "spawns", "objects": [
+   {"name": "player", "type": "player", "x": 8, "y": 24, "point": true},
+   {"name": "rock", "type": "prop", "gid": 5, "x": 16, "y": 32, "width": 16, "height": 16}
+  ]}
+ ]
+}`
+
+func TestReadTiledMapTMX(t *testing.T) {
+	fsys := fstest.MapFS{
+		"levels/test.tmx":  {Data: []byte(testTMX)},
+		"levels/walls.tsx": {Data: []byte(testTSX)},
+	}
+	m, err := ReadTiledMap(fsys, "levels/test.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name() != "test" || m.NumLevels() != 2 || m.Width() != 3 || m.Height() != 2 {
+		t.Fatalf("got %q with %v levels of %vx%v", m.Name(), m.NumLevels(), m.Width(), m.Height())
+	}
+	// Tiled column is X, row is Y
+	// tile 2 has the wall property 6
+	if l := m.Level(0); l[0][0] != 1 || l[0][1] != 1 || l[2][0] != 6 || l[1][0] != 0 {
+		t.Errorf("unexpected ground level %v", l)
+	}
+	// the floor layer is not a level and tile 3 has the floor property 2
+	if m.FloorAt(1, 0) != 2 || m.FloorAt(1, 1) != 2 || m.FloorAt(0, 0) != 0 || m.HasCeiling() {
+		t.Errorf("unexpected floor")
+	}
+	if p := m.PlayerStart(); p.X != 1.5 || p.Y != 0.5 || p.Angle != 90 {
+		t.Errorf("unexpected player start %+v", p)
+	}
+	s := m.Spawns()
+	if len(s) != 1 || s[0].Kind != "enemy" || s[0].Name != "bat" || s[0].X != 2.5 || s[0].Y != 1.5 || s[0].Z != 1.5 {
+		t.Errorf("unexpected spawns %+v", s)
+	}
+}
+
+func TestReadTiledMapTMJ(t *testing.T) {
+	fsys := fstest.MapFS{"test.tmj": {Data: []byte(testTMJ)}}
+	m, err := ReadTiledMap(fsys, "test.tmj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the flipped tile keeps its gid
+	if l := m.Level(0); m.Name() != "tmj" || l[0][0] != 5 || l[1][0] != 5 || l[0][1] != 0 {
+		t.Errorf("unexpected level %q %v", m.Name(), l)
+	}
+	s := m.Spawns()
+	if len(s) != 1 || s[0].Kind != "prop" || s[0].X != 1.5 || s[0].Y != 1.5 {
+		t.Errorf("unexpected spawns %+v", s)
+	}
+}
+
+func TestReadTiledMapNoPlayer(t *testing.T) {
+	fsys := fstest.MapFS{"test.tmj": {Data: []byte(`{"orientation": "orthogonal", "width": 1, "height": 1,
+		"tilewidth": 16, "tileheight": 16, "layers": [{"type": "tilelayer", "data": [0]}]}`)}}
+	if _, err := ReadTiledMap(fsys, "test.tmj"); err == nil {
+		t.Fatal("want error for map without player")
+	}
+}
+
+func TestReadTiledMapSiblingTileset(t *testing.T) {
+	// Tiled keeps tilesets next to the maps directory by default
+	tmx := strings.Replace(testTMX, `source="walls.tsx"`, `source="../tilesets/walls.tsx"`, 1)
+	fsys := fstest.MapFS{
+		"levels/test.tmx":    {Data: []byte(tmx)},
+		"tilesets/walls.tsx": {Data: []byte(testTSX)},
+	}
+	m, err := ReadTiledMap(fsys, "levels/test.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.Level(0); l[2][0] != 6 {
+		t.Errorf("got wall %v, want 6 of the tileset", l[2][0])
+	}
+
+	// above the root of fsys
+	fsys["test.tmx"] = &fstest.MapFile{Data: []byte(tmx)}
+	if _, err := ReadTiledMap(fsys, "test.tmx"); err == nil {
+		t.Error("want error for tileset outside of fsys")
+	}
+}
+
+func TestLoadMapFileSiblingTileset(t *testing.T) {
+	dir := t.TempDir()
+	tmx := strings.Replace(testTMX, `source="walls.tsx"`, `source="../tilesets/walls.tsx"`, 1)
+	for name, data := range map[string]string{
+		filepath.Join("levels", "test.tmx"):    tmx,
+		filepath.Join("tilesets", "walls.tsx"): testTSX,
+	} {
+		fname := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := LoadMapFile(filepath.Join(dir, "levels", "test.tmx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.Level(0); m.Name() != "test" || l[2][0] != 6 {
+		t.Errorf("got %q with wall %v, want test with wall 6 of the tileset", m.Name(), l[2][0])
+	}
+	if want := []string{filepath.Join(dir, "tilesets", "walls.tsx")}; !reflect.DeepEqual(m.Tilesets(), want) {
+		t.Errorf("got tilesets %v, want %v", m.Tilesets(), want)
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "game")
+	levels := filepath.Join(root, "levels")
+	for _, c := range []struct {
+		a, b, want string
+	}{
+		{levels, levels, levels},
+		{levels, filepath.Join(root, "tilesets"), root},
+		{levels, filepath.Join(levels, "sub"), levels},
+		{filepath.Join(levels, "sub"), levels, levels},
+		// not a parent of levels, only a name starting with dots
+		{levels, filepath.Join(levels, "..tilesets"), levels},
+	} {
+		if got := commonDir(c.a, c.b); got != c.want {
+			t.Errorf("commonDir(%v, %v) got %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+	"fmt"
+)
+
+// Future is the answer to a request. The request is sent with a channel of its
+// own as sender, the channel ties the answer to the request, so it can not be
+// mixed up with other messages.
+// The channel holds one message, so the answering reactor never blocks, even
+// when nobody waits for the answer anymore.
+type Future[T IReactorEvent] struct {
+	// the event asked with, it tells requests apart in errors
+	asked IReactorEvent
+	rx    chan ReactorEventMessage
+}
+
+// Request returns the message asking for event and the Future of the answer of type T.
+// Send the message to the reactor that answers.
+func Request[T IReactorEvent](event IReactorEvent) (ReactorEventMessage, *Future[T]) {
+	f := &Future[T]{asked: event, rx: make(chan ReactorEventMessage, 1)}
+	return ReactorEventMessage{f.rx, event}, f
+}
+
+// AskAsync sends event to tx and returns the Future of the answer.
+func AskAsync[T IReactorEvent](tx RcTx, event IReactorEvent) *Future[T] {
+	m, f := Request[T](event)
+	send(tx, m)
+	return f
+}
+
+// Ask sends event to tx and waits for the answer until ctx is done.
+// It blocks, so reactors must not ask the deterministic scheduler runs them on.
+func Ask[T IReactorEvent](ctx context.Context, tx RcTx, event IReactorEvent) (T, error) {
+	return AskAsync[T](tx, event).Await(ctx)
+}
+
+// Await waits for the answer until ctx is done, a late answer is dropped.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	var answer T
+	select {
+	case m := <-f.rx:
+		e, ok := m.event.(T)
+		if !ok {
+			return answer, fmt.Errorf("request %T: got %T, want %T", f.asked, m.event, answer)
+		}
+		return e, nil
+	case <-ctx.Done():
+		return answer, fmt.Errorf("request %T: %w", f.asked, ctx.Err())
+	}
+}
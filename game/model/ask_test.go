@@ -0,0 +1,75 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harbdog/raycaster-go"
+)
+
+func TestAskHealth(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	enemyTx := NewEnemy(coreTx, Position{X: start.X, Y: start.Y}, DrawInfo{}, 1,
+		CollisionSpace{}, 0, 0, raycaster.AnchorBottom, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	health, err := Ask[EventHealth](ctx, enemyTx, EventAskHealth{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Health != fullHealth {
+		t.Errorf("got health %v, want %v", health.Health, fullHealth)
+	}
+}
+
+func TestAskCellFree(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	x, y := int(start.X), int(start.Y)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if free, err := Ask[EventCellFree](ctx, coreTx, EventAskCellFree{X: x, Y: y}); err != nil || !free.Free {
+		t.Errorf("got free %v, %v for the player start, want free", free.Free, err)
+	}
+	if free, err := Ask[EventCellFree](ctx, coreTx, EventAskCellFree{X: -1, Y: y}); err != nil || free.Free {
+		t.Errorf("got free %v, %v outside the map, want a wall", free.Free, err)
+	}
+
+	registerTestRegoter(t, coreTx, Entity{
+		RgId:            <-IdGen,
+		RgType:          RegoterEnumSprite,
+		Position:        Position{X: float64(x) + 0.5, Y: float64(y) + 0.5},
+		CollisionRadius: 0.1,
+		CollisionLayer:  CollisionLayerEnemy,
+	})
+	if free, err := Ask[EventCellFree](ctx, coreTx, EventAskCellFree{X: x, Y: y}); err != nil || free.Free {
+		t.Errorf("got free %v, %v with an enemy in the cell, want taken", free.Free, err)
+	}
+}
+
+func TestAskTimesOut(t *testing.T) {
+	// nobody reads the messages sent to tx
+	tx := make(chan ReactorEventMessage, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := Ask[EventHealth](ctx, tx, EventAskHealth{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	// the error tells which request timed out
+	if !strings.Contains(err.Error(), "EventAskHealth") {
+		t.Errorf("got error %q, want it to name EventAskHealth", err)
+	}
+}
+
+func TestAskChecksAnswerType(t *testing.T) {
+	m, f := Request[EventHealth](EventAskHealth{})
+	m.sender <- ReactorEventMessage{nil, EventDrawDone{}}
+	if _, err := f.Await(context.Background()); err == nil {
+		t.Error("got no error for an answer of the wrong type")
+	}
+}
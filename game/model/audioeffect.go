@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"lintech/rego/game/loader"
@@ -24,40 +25,52 @@ type RegoAudioPlayer struct {
 	audioFile fs.File
 }
 
+// LoadAudioPlayer loads the audio file fname of the assets.
+// A file that can not be played is reported and nil is returned, a nil player stays silent.
 func LoadAudioPlayer(fname string) *RegoAudioPlayer {
 	if len(fname) == 0 {
 		return nil
 	}
 
-	f := loader.LoadAudioFile(fname)
+	f, err := loader.LoadAudioFile(fname)
+	if err != nil {
+		log.Printf("Warning: Load audio file fail: %v", err)
+		return nil
+	}
 	var d io.Reader
-	var err error
 	switch ext := path.Ext(fname); ext {
 	case ".wav":
 		d, err = wav.DecodeWithSampleRate(audioContextSampleRate, f)
 	case ".mp3":
 		d, err = mp3.DecodeWithSampleRate(audioContextSampleRate, f)
 	default:
-		log.Fatal("Unsupported audo file ext ", ext)
+		err = fmt.Errorf("unsupported audio file ext %q", ext)
 	}
 	if err != nil {
-		log.Fatalf("Decode audio file fail: %e", err)
+		log.Printf("Warning: Decode audio file %q fail: %v", fname, err)
+		f.Close()
+		return nil
 	}
 	// Create an audio.Player that has one stream.
 	audioPlayer, err := audioContext.NewPlayer(d)
 	if err != nil {
-		log.Fatalf("Create audio player fail: %e", err)
+		log.Printf("Warning: Create audio player fail: %v", err)
+		f.Close()
+		return nil
 	}
 	return &RegoAudioPlayer{player: audioPlayer, audioFile: f}
 }
 
 func (a *RegoAudioPlayer) Close() {
+	if a == nil {
+		return
+	}
 	a.player.Close()
 	a.audioFile.Close()
 }
 
 func (a *RegoAudioPlayer) Play(audioPosition, playerPosition Position, renderAudioDistance float64) {
-	if a.player.IsPlaying() {
+	if a == nil || a.player.IsPlaying() {
 		return
 	}
 	if err := a.player.Rewind(); err != nil {
@@ -74,7 +87,7 @@ func (a *RegoAudioPlayer) Play(audioPosition, playerPosition Position, renderAud
 }
 
 func (a *RegoAudioPlayer) PlayWithVolume(volume float64, forcePlay bool) {
-	if a.player.IsPlaying() && !forcePlay {
+	if a == nil || (a.player.IsPlaying() && !forcePlay) {
 		return
 	}
 	if err := a.player.Rewind(); err != nil {
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"sync"
+)
+
+// Topic names the events published on a Bus.
+type Topic string
+
+const (
+	// EventCfgChanged, every regoter is subscribed by Core
+	TopicConfig Topic = "config"
+	// EventPlayerDamaged
+	TopicPlayerDamaged Topic = "player.damaged"
+	// EventEnemyDied
+	TopicEnemyDied Topic = "enemy.died"
+)
+
+// Bus hands the events published on a topic to the reactors subscribed to it,
+// so publishers do not know who listens. Core keeps the Bus of its world and
+// gives it to regoters with EventCfgChanged and to others with EventAskBus.
+// Publishing sends from the goroutine of the publisher, in the order of subscription.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[Topic][]RcTx
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[Topic][]RcTx{}}
+}
+
+// Subscribe makes the events published on topic arrive at tx.
+func (b *Bus) Subscribe(topic Topic, tx RcTx) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.subs[topic] {
+		if s == tx {
+			return
+		}
+	}
+	b.subs[topic] = append(b.subs[topic], tx)
+}
+
+// Unsubscribe ends the subscription of tx to topic.
+func (b *Bus) Unsubscribe(topic Topic, tx RcTx) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.unsubscribe(topic, tx)
+}
+
+// UnsubscribeAll ends the subscriptions of tx to all topics.
+func (b *Bus) UnsubscribeAll(tx RcTx) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for topic := range b.subs {
+		b.unsubscribe(topic, tx)
+	}
+}
+
+func (b *Bus) unsubscribe(topic Topic, tx RcTx) {
+	subs := b.subs[topic]
+	for i, s := range subs {
+		if s == tx {
+			// a copy, Publish may still send to the old subscribers
+			b.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish sends event from sender to the subscribers of topic.
+// Publishing on a nil Bus does nothing, so regoters may publish before they got one.
+func (b *Bus) Publish(sender RcTx, topic Topic, event IReactorEvent) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := b.subs[topic]
+	b.mu.RUnlock()
+	for _, tx := range subs {
+		send(tx, ReactorEventMessage{sender, event})
+	}
+}
+
+func (g *Core) eventHandleAskBus(sender RcTx, e EventAskBus) {
+	send(sender, ReactorEventMessage{g.tx, EventBus{Bus: g.bus}})
+}
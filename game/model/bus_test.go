@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harbdog/raycaster-go"
+)
+
+func TestBusPublishesToSubscribers(t *testing.T) {
+	bus := NewBus()
+	damaged := make(chan ReactorEventMessage, 10)
+	died := make(chan ReactorEventMessage, 10)
+	bus.Subscribe(TopicPlayerDamaged, damaged)
+	bus.Subscribe(TopicPlayerDamaged, damaged)
+	bus.Subscribe(TopicEnemyDied, died)
+
+	bus.Publish(nil, TopicPlayerDamaged, EventPlayerDamaged{Damage: 1})
+	if e := expectEvent[EventPlayerDamaged](t, damaged); e.Damage != 1 {
+		t.Errorf("got damage %v, want 1", e.Damage)
+	}
+	if len(damaged) != 0 || len(died) != 0 {
+		t.Errorf("got %v more damages and %v deaths, want none", len(damaged), len(died))
+	}
+
+	bus.UnsubscribeAll(damaged)
+	bus.Publish(nil, TopicPlayerDamaged, EventPlayerDamaged{Damage: 2})
+	bus.Publish(nil, TopicEnemyDied, EventEnemyDied{RgId: 3})
+	if len(damaged) != 0 {
+		t.Error("got a damage after unsubscribing")
+	}
+	if e := expectEvent[EventEnemyDied](t, died); e.RgId != 3 {
+		t.Errorf("got death of %v, want 3", e.RgId)
+	}
+}
+
+func TestConfigIsPublished(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	rx := registerTestRegoter(t, coreTx, Entity{RgId: <-IdGen, RgType: RegoterEnumSprite,
+		Position: Position{X: start.X, Y: start.Y}})
+	coreTx <- ReactorEventMessage{nil, EventCfgChanged{Cfg: GameCfg{Debug: true}}}
+	if e := expectEvent[EventCfgChanged](t, rx); !e.Cfg.Debug || e.Bus == nil {
+		t.Errorf("got %+v, want the new config and the bus", e)
+	}
+}
+
+func TestEnemyDiedIsPublished(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	enemyTx := NewEnemy(coreTx, Position{X: start.X, Y: start.Y}, DrawInfo{}, 1,
+		CollisionSpace{}, 0, 0, raycaster.AnchorBottom, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// Core sent the enemy its config with the bus before it answers
+	bus, err := Ask[EventBus](ctx, coreTx, EventAskBus{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	died := make(chan ReactorEventMessage, 1)
+	bus.Bus.Subscribe(TopicEnemyDied, died)
+
+	enemyTx <- ReactorEventMessage{coreTx, EventHealthChange{change: fullHealth + 1}}
+	expectEvent[EventEnemyDied](t, died)
+}
+
+func TestHudFollowsTheBus(t *testing.T) {
+	coreTx, start := newHeadlessTestCoreWith(t, GameCfg{Deterministic: true, Seed: 1})
+	world := WorldOf(coreTx)
+	hud := NewHud(coreTx)
+	enemyTx := NewEnemy(coreTx, Position{X: start.X, Y: start.Y}, DrawInfo{}, 1,
+		CollisionSpace{}, 0, 0, raycaster.AnchorBottom, nil)
+	world.Settle()
+
+	enemyTx <- ReactorEventMessage{coreTx, EventHealthChange{change: fullHealth + 1}}
+	hud.bus.Publish(nil, TopicPlayerDamaged, EventPlayerDamaged{Damage: 10, Health: playerHealth - 10})
+	world.Settle()
+	if hud.Kills() != 1 || hud.Health() != playerHealth-10 {
+		t.Errorf("got %v kills and health %v, want 1 kill and health %v", hud.Kills(), hud.Health(), playerHealth-10)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := hud.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	hud.bus.Publish(nil, TopicEnemyDied, EventEnemyDied{})
+	if n := len(hud.rx); n != 0 {
+		t.Errorf("got %v events after the HUD stopped, want none", n)
+	}
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"image"
+	"image/color"
+	"lintech/rego/game/loader"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// raycaster-go only draws a skybox above the horizon, so ceilings are cast here.
+// When the level has ceilings the camera sky is disabled and drawCeiling draws
+// the sky and the ceiling to the scene before the camera view is drawn on top.
+// The casting mirrors the textured floor of the camera for a ceiling at height 1,
+// on the CPU, so it is cached while the view stays the same.
+
+// ceilingView is what the cast ceiling depends on. The ceiling is only cast
+// again when it changes, a player standing still costs no CPU.
+type ceilingView struct {
+	x, y, z            float64
+	pitch, horizon     int
+	dirX, dirY         float64
+	planeX, planeY     float64
+	w, h               int
+	renderDistance     float64
+	lightFalloff       float64
+	globalIllumination float64
+	minLight, maxLight color.NRGBA
+	// textures and level of the ceiling, both replaced on reload
+	tex    *loader.TextureHandler
+	mapObj *loader.Map
+}
+
+func (g *Core) setCeilingSize(w, h int) {
+	g.ceilingBuf = image.NewRGBA(image.Rect(0, 0, w, h))
+	g.ceilingImg = ebiten.NewImage(w, h)
+	g.ceilingView = ceilingView{}
+}
+
+func (g *Core) drawCeiling(scene *ebiten.Image) {
+	player := g.getPlayer()
+	if g.sky == nil || player == nil {
+		return
+	}
+
+	w, h := g.cfg.Width, g.cfg.Height
+	pos := g.camera.GetPosition()
+	fovDepth := g.camera.FovDepth()
+	fovAngle := geom.Radians(g.camera.FovAngle())
+	angle := player.entity.Angle
+	pitch := geom.ClampInt(int(geom.GetOppositeTriangleLeg(player.entity.Pitch, float64(h)*fovDepth)),
+		-h/2, int(float64(h)*fovDepth))
+
+	// camera direction and plane vectors, as in raycaster.Camera
+	dirX, dirY := fovDepth*math.Cos(angle), fovDepth*math.Sin(angle)
+	hypotenuse := fovDepth / math.Cos(fovAngle/2)
+	view := ceilingView{
+		x:                  pos.X,
+		y:                  pos.Y,
+		z:                  g.camera.GetPositionZ(),
+		pitch:              pitch,
+		horizon:            geom.ClampInt(h/2+pitch, 0, h),
+		dirX:               dirX,
+		dirY:               dirY,
+		planeX:             dirX - hypotenuse*math.Cos(angle+fovAngle/2),
+		planeY:             dirY - hypotenuse*math.Sin(angle+fovAngle/2),
+		w:                  w,
+		h:                  h,
+		renderDistance:     g.cfg.RenderDistance,
+		lightFalloff:       g.cfg.LightFalloff,
+		globalIllumination: g.cfg.GlobalIllumination,
+		minLight:           g.cfg.MinLightRGB,
+		maxLight:           g.cfg.MaxLightRGB,
+		tex:                g.tex,
+		mapObj:             g.mapObj,
+	}
+	if view != g.ceilingView {
+		g.castCeiling(view)
+		g.ceilingImg.WritePixels(g.ceilingBuf.Pix)
+		g.ceilingView = view
+	}
+
+	// sky above the horizon, where there is no ceiling
+	op := &ebiten.DrawImageOptions{}
+	op.Filter = ebiten.FilterNearest
+	skyBounds := g.sky.Bounds()
+	op.GeoM.Scale(float64(w)/float64(skyBounds.Dx()), float64(view.horizon)/float64(skyBounds.Dy()))
+	scene.DrawImage(g.sky, op)
+
+	scene.DrawImage(g.ceilingImg, nil)
+}
+
+// castCeiling casts the ceiling seen in view to ceilingBuf.
+func (g *Core) castCeiling(view ceilingView) {
+	w, h := view.w, view.h
+	camZ := (view.z - 0.5) * float64(h)
+	renderDistance := view.renderDistance
+	if renderDistance < 0 {
+		renderDistance = math.MaxFloat64
+	}
+
+	buf := g.ceilingBuf
+	for i := range buf.Pix {
+		buf.Pix[i] = 0
+	}
+	for y := 0; y < view.horizon; y++ {
+		// distance to the ceiling seen on this row
+		rowDist := (float64(h) - 2.0*camZ) / (float64(h) - 2.0*float64(y-view.pitch))
+		if rowDist > renderDistance {
+			continue
+		}
+
+		// lighting
+		shadowDepth := math.Sqrt(rowDist) * view.lightFalloff
+		light := func(min, max uint8) float64 {
+			return float64(geom.ClampInt(int(255+shadowDepth+view.globalIllumination), int(min), int(max)))
+		}
+		lr := light(view.minLight.R, view.maxLight.R)
+		lg := light(view.minLight.G, view.maxLight.G)
+		lb := light(view.minLight.B, view.maxLight.B)
+
+		// walk from the leftmost to the rightmost ray of the row
+		ceilX := view.x + rowDist*(view.dirX-view.planeX)
+		ceilY := view.y + rowDist*(view.dirY-view.planeY)
+		stepX := rowDist * 2 * view.planeX / float64(w)
+		stepY := rowDist * 2 * view.planeY / float64(w)
+		for x := 0; x < w; x, ceilX, ceilY = x+1, ceilX+stepX, ceilY+stepY {
+			if ceilX < 0 || ceilY < 0 {
+				continue
+			}
+			tex := view.tex.CeilingTextureAt(int(ceilX), int(ceilY))
+			if tex == nil {
+				continue
+			}
+
+			texX := int(ceilX*loader.TexWidth) % loader.TexWidth
+			texY := int(ceilY*loader.TexWidth) % loader.TexWidth
+			texOffset := tex.PixOffset(texX, texY)
+			bufOffset := buf.PixOffset(x, y)
+			buf.Pix[bufOffset] = uint8(float64(tex.Pix[texOffset]) * lr / 256)
+			buf.Pix[bufOffset+1] = uint8(float64(tex.Pix[texOffset+1]) * lg / 256)
+			buf.Pix[bufOffset+2] = uint8(float64(tex.Pix[texOffset+2]) * lb / 256)
+			buf.Pix[bufOffset+3] = tex.Pix[texOffset+3]
+		}
+	}
+}
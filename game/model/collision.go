@@ -1,148 +1,165 @@
 package model
 
 import (
+	"lintech/rego/game/loader"
+	"math"
 	"sort"
 
 	"github.com/harbdog/raycaster-go"
 	"github.com/harbdog/raycaster-go/geom"
+	"github.com/harbdog/raycaster-go/geom3d"
 )
 
-// checks for valid move from current position, returns valid (x, y) position, whether a collision
-// was encountered, and a list of entity collisions that may have been encountered
-// NOTE: Bug??
-// When collision with Wall, the []*EntityCollision is empty!
+// number of times a blocked move may slide along what it hit
+const maxSlides = 3
+
+// distance kept to what a sliding move hit, so the slide does not start touching it
+const slideGap = 1e-6
+
+// moveHit is a collision on the way of a move.
+type moveHit struct {
+	collision EntityCollision
+	// line param (0 to 1) of the move where it hits
+	t float64
+}
+
+// checks for valid move from current position, returns valid (x, y) position and the
+// contacts on the way of the move, nearest first. With checkAlternate a blocked move
+// slides along the wall or around the sprite it hit, else it does not move at all.
 func (g *Core) getValidMove(entity *Entity,
-	moveX, moveY, moveZ float64, checkAlternate bool) (*geom.Vector2, *EntityCollision) {
+	moveX, moveY, moveZ float64, checkAlternate bool) (*geom.Vector2, []EntityCollision) {
 
 	posX, posY, posZ := entity.Position.X, entity.Position.Y, entity.Position.Z
 	if posX == moveX && posY == moveY && posZ == moveZ {
 		return &geom.Vector2{X: posX, Y: posY}, nil
 	}
 
-	newX, newY, newZ := moveX, moveY, moveZ
-	moveLine := geom.Line{X1: posX, Y1: posY, X2: newX, Y2: newY}
+	start, end := entity.Position, Position{X: moveX, Y: moveY, Z: moveZ}
+	hits := g.moveHits(entity, start, end)
+	if len(hits) == 0 {
+		return &geom.Vector2{X: moveX, Y: moveY}, nil
+	}
+	contacts := make([]EntityCollision, len(hits))
+	for i, hit := range hits {
+		contacts[i] = hit.collision
+	}
+	if !checkAlternate {
+		// If there is collistion, don't move!
+		return &geom.Vector2{X: posX, Y: posY}, contacts
+	}
 
-	collisionEntities := make([]*EntityCollision, 0, 10)
+	for i := 0; len(hits) > 0; i++ {
+		if i == maxSlides {
+			// stuck in a corner, stay where the last slide stopped
+			end = start
+			break
+		}
+		// move up to the hit, then along its tangent with what is left of the move
+		hit := hits[0]
+		normal := geom.Vector2{X: hit.collision.normal.X, Y: hit.collision.normal.Y}
+		at := Position{
+			X: start.X + hit.t*(end.X-start.X) + normal.X*slideGap,
+			Y: start.Y + hit.t*(end.Y-start.Y) + normal.Y*slideGap,
+			Z: end.Z,
+		}
+		restX, restY := end.X-at.X, end.Y-at.Y
+		if into := restX*normal.X + restY*normal.Y; into < 0 {
+			restX -= into * normal.X
+			restY -= into * normal.Y
+		}
+		start, end = at, Position{X: at.X + restX, Y: at.Y + restY, Z: end.Z}
+		hits = g.moveHits(entity, start, end)
+	}
+	return &geom.Vector2{X: end.X, Y: end.Y}, contacts
+}
+
+// moveHits returns the collisions of entity moving from start to end ordered by
+// distance, up to the first wall as nothing gets past one.
+func (g *Core) moveHits(entity *Entity, start, end Position) []moveHit {
+	posX, posY, posZ := start.X, start.Y, start.Z
+	newX, newY, newZ := end.X, end.Y, end.Z
+	moveLine := geom.Line{X1: posX, Y1: posY, X2: newX, Y2: newY}
 
-	// check wall collisions
-	for _, borderLine := range g.collisionMap {
-		// TODO: only check intersection of nearby wall cells instead of all of them
-		if px, py, ok := geom.LineIntersection(moveLine, borderLine); ok {
-			point := geom.Vector2{X: px, Y: py}
-			collisionEntities = append(
+	hits := make([]moveHit, 0, 10)
+	moveLength := math.Sqrt(geom.Distance2(posX, posY, newX, newY) + (newZ-posZ)*(newZ-posZ))
+
+	// check wall collisions at the levels the entity is in
+	hitsWalls := entity.collisionMask()&CollisionLayerWall != 0
+	if hitsWalls && isCollisionSphere(entity) {
+		// spheres fly at any pitch, so walls are hit at the height on the way
+		below, above := collisionExtent(entity)
+		line := geom3d.Line3d{X1: posX, Y1: posY, Z1: posZ, X2: newX, Y2: newY, Z2: newZ}
+		if hit, ok := g.mapObj.WallHit3d(line, below, above, loader.ClipDistance); ok {
+			point := hit.Point
+			distance := math.Sqrt(geom.Distance2(posX, posY, point.X, point.Y) + (point.Z-posZ)*(point.Z-posZ))
+			hits = append(hits, moveHit{
 				// Collistion with wall, RcTx is nil
-				collisionEntities, &EntityCollision{
-					position: Position{X: point.X, Y: point.Y, Z: newZ},
+				collision: EntityCollision{
+					position: Position{X: point.X, Y: point.Y, Z: point.Z},
 					peer:     WALL_ID,
-					distance: geom.Distance2(posX, posY, point.X, point.Y),
+					distance: distance,
+					normal:   hit.Normal,
 				},
-			)
+				t: distance / moveLength,
+			})
 		}
-	}
-
-	// check sprite against player collision
-	playerInCore := g.getPlayer()
-	player := playerInCore.sprite
-	if nil != player && entity.RgId != player.Entity.RgId &&
-		entity.ParentId != player.Entity.RgId && entity.CollisionRadius > 0 {
-		// TODO: only check for collision if player is somewhat nearby
-
-		// quick check if intersects in Z-plane
-		zIntersect := zEntityIntersection(newZ, entity, player.Entity)
-
-		// check if movement line intersects with combined collision radii
-		combinedCircle := geom.Circle{X: player.Entity.Position.X, Y: player.Entity.Position.Y,
-			Radius: player.Entity.CollisionRadius + entity.CollisionRadius}
-		combinedIntersects := geom.LineCircleIntersection(moveLine, combinedCircle, true)
-
-		if zIntersect >= 0 && len(combinedIntersects) > 0 {
-			playerCircle := geom.Circle{X: player.Entity.Position.X, Y: player.Entity.Position.Y, Radius: player.Entity.CollisionRadius}
-			for _, chkPoint := range combinedIntersects {
-				// intersections from combined circle radius indicate center point to check intersection toward sprite collision circle
-				chkLine := geom.Line{X1: chkPoint.X, Y1: chkPoint.Y, X2: player.Entity.Position.X, Y2: player.Entity.Position.Y}
-				intersectPoints := geom.LineCircleIntersection(chkLine, playerCircle, true)
-				for _, point := range intersectPoints {
-					collisionEntities = append(
-						//collisionEntities, &EntityCollision{entity: player.Entity, collision: &intersect, collisionZ: zIntersect},
-						collisionEntities, &EntityCollision{
-							position: Position{X: point.X, Y: point.Y, Z: zIntersect},
-							peer:     playerInCore.entity.RgId,
-						},
-					)
-				}
-			}
+	} else if hitsWalls {
+		minZ, maxZ := zEntityMinMax(newZ, entity)
+		if hit, ok := g.mapObj.WallHit(moveLine, minZ, maxZ, loader.ClipDistance); ok {
+			point := hit.Point
+			distance := geom.Distance(posX, posY, point.X, point.Y)
+			hits = append(hits, moveHit{
+				// Collistion with wall, RcTx is nil
+				collision: EntityCollision{
+					position: Position{X: point.X, Y: point.Y, Z: newZ},
+					peer:     WALL_ID,
+					distance: distance,
+					normal:   geom3d.Vector3{X: hit.Normal.X, Y: hit.Normal.Y},
+				},
+				t: distance / moveLength,
+			})
 		}
 	}
 
-	// check sprite collisions
-	l := g.rgs[RegoterEnumSprite]
-	for _, r := range l {
-		sprite := r.sprite
-		// TODO: only check intersection of nearby sprites instead of all of them
-		if entity.RgId == sprite.Entity.RgId || entity.ParentId == sprite.Entity.RgId ||
-			entity.RgId == sprite.Entity.ParentId ||
-			entity.CollisionRadius <= 0 || sprite.Entity.CollisionRadius <= 0 {
-			continue
+	collide := func(target *regoterInCore) {
+		peer := &target.entity
+		// nothing collides with itself or its parent
+		if entity.RgId == peer.RgId || entity.ParentId == peer.RgId || entity.RgId == peer.ParentId ||
+			entity.CollisionRadius <= 0 || peer.CollisionRadius <= 0 || !entity.collidesWith(peer) {
+			return
 		}
-
-		// quick check if intersects in Z-plane
-		zIntersect := zEntityIntersection(newZ, entity, sprite.Entity)
-
-		// check if movement line intersects with combined collision radii
-		combinedCircle := geom.Circle{X: sprite.Entity.Position.X, Y: sprite.Entity.Position.Y,
-			Radius: sprite.Entity.CollisionRadius + entity.CollisionRadius}
-		combinedIntersects := geom.LineCircleIntersection(moveLine, combinedCircle, true)
-
-		if zIntersect >= 0 && len(combinedIntersects) > 0 {
-			spriteCircle := geom.Circle{X: sprite.Entity.Position.X, Y: sprite.Entity.Position.Y, Radius: sprite.Entity.CollisionRadius}
-			for _, chkPoint := range combinedIntersects {
-				// intersections from combined circle radius indicate center point to check intersection toward sprite collision circle
-				chkLine := geom.Line{X1: chkPoint.X, Y1: chkPoint.Y, X2: sprite.Entity.Position.X, Y2: sprite.Entity.Position.Y}
-				intersectPoints := geom.LineCircleIntersection(chkLine, spriteCircle, true)
-
-				for _, point := range intersectPoints {
-					collisionEntities = append(
-						//collisionEntities, &EntityCollision{entity: sprite.Entity, collision: &intersect, collisionZ: zIntersect},
-						collisionEntities, &EntityCollision{
-							position: Position{X: point.X, Y: point.Y, Z: zIntersect},
-							peer:     r.entity.RgId,
-						},
-					)
-				}
-			}
+		if t, point, normal, ok := sweptHit(entity, start, end, peer); ok {
+			hits = append(hits, moveHit{
+				collision: EntityCollision{
+					position: point,
+					peer:     peer.RgId,
+					distance: t * moveLength,
+					normal:   normal,
+				},
+				t: t,
+			})
 		}
 	}
 
-	isCollision := len(collisionEntities) > 0
-	if isCollision {
-		// sort collisions by distance to current entity position
-		sort.Slice(collisionEntities, func(i, j int) bool {
-			return collisionEntities[i].distance < collisionEntities[j].distance
-		})
-		// If there is collistion, don't move!
-		return &geom.Vector2{X: posX, Y: posY}, collisionEntities[0]
-	} else {
-		return &geom.Vector2{X: newX, Y: newY}, nil
+	// check collisions with the entities near the move line on the layers of the mask
+	for _, r := range g.regotersNearLine(moveLine, entity.CollisionRadius, entity.collisionMask()) {
+		collide(r)
 	}
 
-}
-
-// zEntityIntersection returns the best Position.Z intersection point on the target from the source (-1 if no intersection)
-func zEntityIntersection(sourceZ float64, source, target *Entity) float64 {
-	srcMinZ, srcMaxZ := zEntityMinMax(sourceZ, source)
-	tgtMinZ, tgtMaxZ := zEntityMinMax(target.Position.Z, target)
-
-	var intersectZ float64 = -1
-	if srcMinZ > tgtMaxZ || tgtMinZ > srcMaxZ {
-		// no intersection
-		return intersectZ
+	// sort collisions by distance to current entity position,
+	// the candidates come in map order, so ties are sorted by ID
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].t != hits[j].t {
+			return hits[i].t < hits[j].t
+		}
+		return hits[i].collision.peer < hits[j].collision.peer
+	})
+	for i, hit := range hits {
+		if hit.collision.peer == WALL_ID {
+			return hits[:i+1]
+		}
 	}
-
-	// find best simple intersection within the target range
-	midZ := srcMinZ + (srcMaxZ-srcMinZ)/2
-	intersectZ = geom.Clamp(midZ, tgtMinZ, tgtMaxZ)
-
-	return intersectZ
+	return hits
 }
 
 // zEntityMinMax calculates the minZ/maxZ used for basic collision checking in the Z-plane
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"lintech/rego/game/loader"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/harbdog/raycaster-go"
+	"github.com/harbdog/raycaster-go/geom3d"
+)
+
+// roomLevel is a room of 4x4 free cells with the walls (2, 3) and (4, 3)
+// inside, which leave the cell (3, 3) as a gap between them.
+const roomLevel = `{"name": "room", "width": 6, "height": 6, "player": {"x": 1.5, "y": 1.5},
+	"levels": [[
+		[1, 1, 1, 1, 1, 1],
+		[1, 0, 0, 0, 0, 1],
+		[1, 0, 0, 1, 0, 1],
+		[1, 0, 0, 0, 0, 1],
+		[1, 0, 0, 1, 0, 1],
+		[1, 1, 1, 1, 1, 1]]]}`
+
+// roomTestCore returns a Core in roomLevel that nothing is registered with.
+func roomTestCore(t *testing.T) *Core {
+	t.Helper()
+	m, err := loader.ReadMap(strings.NewReader(roomLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newCore(GameCfg{})
+	g.setMap(m)
+	return g
+}
+
+func walker(x, y float64) *Entity {
+	return &Entity{
+		RgId:            1,
+		RgType:          RegoterEnumSprite,
+		Position:        Position{X: x, Y: y},
+		Anchor:          raycaster.AnchorBottom,
+		CollisionRadius: 0.2,
+		CollisionHeight: 0.5,
+		CollisionLayer:  CollisionLayerEnemy,
+		CollisionMask:   CollisionLayerWall,
+	}
+}
+
+func TestWallSliding(t *testing.T) {
+	g := roomTestCore(t)
+	wall := loader.ClipDistance
+	for _, c := range []struct {
+		name         string
+		from, to     Position
+		want         Position
+		normal       geom3d.Vector3
+		withContacts bool
+	}{
+		// into the wall at y 0 at an angle, sliding along it
+		{"straight", Position{X: 2.5, Y: 1.5}, Position{X: 2.8, Y: 0.9},
+			Position{X: 2.8, Y: 1 + wall}, geom3d.Vector3{Y: 1}, true},
+		// into the corner of the walls at x 0 and y 0, stopping there
+		{"corner", Position{X: 1.5, Y: 1.5}, Position{X: 0.8, Y: 0.9},
+			Position{X: 1 + wall, Y: 1 + wall}, geom3d.Vector3{X: 1}, true},
+		// diagonally through the gap at (3, 3)
+		{"gap", Position{X: 3, Y: 2.4}, Position{X: 3.8, Y: 4.4},
+			Position{X: 3.8, Y: 4.4}, geom3d.Vector3{}, false},
+		// missing the gap, sliding along the wall next to it
+		{"gap edge", Position{X: 2.6, Y: 2.4}, Position{X: 3.4, Y: 4.4},
+			Position{X: 3.4, Y: 3 - wall}, geom3d.Vector3{Y: -1}, true},
+	} {
+		e := walker(c.from.X, c.from.Y)
+		pos, contacts := g.getValidMove(e, c.to.X, c.to.Y, 0, true)
+		if math.Abs(pos.X-c.want.X) > 1e-5 || math.Abs(pos.Y-c.want.Y) > 1e-5 {
+			t.Errorf("%v: got (%v, %v), want (%v, %v)", c.name, pos.X, pos.Y, c.want.X, c.want.Y)
+		}
+		if !c.withContacts {
+			if len(contacts) != 0 {
+				t.Errorf("%v: got contacts %+v, want none", c.name, contacts)
+			}
+			continue
+		}
+		if len(contacts) != 1 || contacts[0].peer != WALL_ID || contacts[0].normal != c.normal {
+			t.Errorf("%v: got contacts %+v, want the wall with normal %+v", c.name, contacts, c.normal)
+		}
+	}
+}
+
+func TestCornerDoesNotJitter(t *testing.T) {
+	g := roomTestCore(t)
+	e := walker(1.5, 1.5)
+	var stop *Position
+	for i := 0; i < 5; i++ {
+		// keep pushing into the corner
+		pos, _ := g.getValidMove(e, e.Position.X-0.7, e.Position.Y-0.6, 0, true)
+		e.Position.X, e.Position.Y = pos.X, pos.Y
+		if stop == nil {
+			stop = &Position{X: pos.X, Y: pos.Y}
+			continue
+		}
+		if math.Abs(pos.X-stop.X) > 1e-9 || math.Abs(pos.Y-stop.Y) > 1e-9 {
+			t.Fatalf("move %v: got (%v, %v), want to stay at (%v, %v)", i, pos.X, pos.Y, stop.X, stop.Y)
+		}
+	}
+}
+
+func TestCollisionReachesEveryPeer(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	mover := Entity{
+		RgId:            <-IdGen,
+		RgType:          RegoterEnumSprite,
+		Position:        Position{X: start.X, Y: start.Y},
+		Anchor:          raycaster.AnchorBottom,
+		Velocity:        0.9,
+		CollisionRadius: 0.2,
+		CollisionHeight: 0.5,
+		CollisionLayer:  CollisionLayerEnemy,
+		CollisionMask:   CollisionLayerEnemy,
+	}
+	near, far := mover, mover
+	near.RgId, far.RgId = <-IdGen, <-IdGen
+	near.Velocity, far.Velocity = 0, 0
+	near.Position.X += 0.5
+	far.Position.X += 1
+	moverRx := registerTestRegoter(t, coreTx, mover)
+	nearRx := registerTestRegoter(t, coreTx, near)
+	farRx := registerTestRegoter(t, coreTx, far)
+
+	coreTx <- ReactorEventMessage{moverRx, EventMovement{RgId: mover.RgId}}
+	contacts := expectEvent[EventCollision](t, moverRx).contacts
+	if len(contacts) != 2 {
+		t.Fatalf("got contacts %+v, want 2", contacts)
+	}
+	for i, c := range []struct {
+		peer     ID
+		distance float64
+		rx       chan ReactorEventMessage
+	}{
+		{near.RgId, 0.1, nearRx},
+		{far.RgId, 0.6, farRx},
+	} {
+		got := contacts[i]
+		if got.peer != c.peer || math.Abs(got.distance-c.distance) > 1e-9 || got.normal != (geom3d.Vector3{X: -1}) {
+			t.Errorf("contact %v: got %+v, want peer %v at distance %v with normal -X", i, got, c.peer, c.distance)
+		}
+		// the peer gets the contact mirrored
+		peerContacts := expectEvent[EventCollision](t, c.rx).contacts
+		if len(peerContacts) != 1 || peerContacts[0].peer != mover.RgId ||
+			peerContacts[0].distance != got.distance || peerContacts[0].position != got.position ||
+			peerContacts[0].normal != (geom3d.Vector3{X: 1}) {
+			t.Errorf("peer %v: got %+v, want the contact with %v at distance %v with normal +X",
+				c.peer, peerContacts, mover.RgId, c.distance)
+		}
+	}
+}
+
+func TestCollisionLayers(t *testing.T) {
+	const (
+		ghostMask = CollisionLayerWall | CollisionLayerPlayer
+		// the player's mask, see NewPlayer
+		playerMask = CollisionLayerWall | CollisionLayerEnemy | CollisionLayerPickup
+	)
+	mover := func(layer, mask CollisionLayer) *Entity {
+		e := walker(1.5, 2.5)
+		e.CollisionLayer, e.CollisionMask = layer, mask
+		return e
+	}
+	projectile := mover(CollisionLayerProjectile, playerProjectileMask)
+	projectile.RgType = RegoterEnumProjectile
+	projectile.Position.Z = 0.25
+
+	for _, c := range []struct {
+		name   string
+		mover  *Entity
+		target CollisionLayer
+		want   bool
+	}{
+		{"ghost through enemy", mover(CollisionLayerEnemy, ghostMask), CollisionLayerEnemy, false},
+		{"enemy into ghost", mover(CollisionLayerEnemy, enemyCollisionMask), CollisionLayerEnemy, true},
+		{"projectile through player", projectile, CollisionLayerPlayer, false},
+		{"projectile into enemy", projectile, CollisionLayerEnemy, true},
+		{"player onto pickup", mover(CollisionLayerPlayer, playerMask), CollisionLayerPickup, true},
+		{"enemy over pickup", mover(CollisionLayerEnemy, enemyCollisionMask), CollisionLayerPickup, false},
+		{"projectile over pickup", projectile, CollisionLayerPickup, false},
+		{"no mask into enemy", mover(CollisionLayerEnemy, 0), CollisionLayerEnemy, true},
+		// an entity without a layer is on all of them
+		{"projectile into no layer", projectile, 0, true},
+		{"ghost into no layer", mover(CollisionLayerEnemy, ghostMask), 0, true},
+	} {
+		g := roomTestCore(t)
+		target := walker(2.5, 2.5)
+		target.RgId = 2
+		target.CollisionLayer = c.target
+		g.spatial.insert(&regoterInCore{rgType: target.RgType, entity: *target})
+
+		if got := c.mover.collidesWith(target); got != c.want {
+			t.Errorf("%v: collidesWith got %v, want %v", c.name, got, c.want)
+		}
+		e := *c.mover
+		_, contacts := g.getValidMove(&e, 3.5, 2.5, e.Position.Z, !isCollisionSphere(&e))
+		if hit := len(contacts) > 0 && contacts[0].peer == target.RgId; hit != c.want {
+			t.Errorf("%v: got contacts %+v, want a hit %v", c.name, contacts, c.want)
+		}
+	}
+}
+
+func TestNoMaskStopsAtWalls(t *testing.T) {
+	g := roomTestCore(t)
+	e := walker(2.5, 1.5)
+	e.CollisionMask = 0
+	pos, contacts := g.getValidMove(e, 2.5, 0.5, 0, true)
+	if len(contacts) != 1 || contacts[0].peer != WALL_ID || math.Abs(pos.Y-(1+loader.ClipDistance)) > 1e-5 {
+		t.Errorf("got (%v, %v) with contacts %+v, want to stop at the wall", pos.X, pos.Y, contacts)
+	}
+}
@@ -34,6 +34,8 @@ func (c *cooldownFlag) cooldown() {
 
 type ICooldownInt interface {
 	add(int) int
+	get() int
+	set(int)
 	cooldown()
 }
 
@@ -56,6 +58,10 @@ func (c *cooldownInt) get() int {
 	return c.value
 }
 
+func (c *cooldownInt) set(v int) {
+	c.value = v
+}
+
 func (c *cooldownInt) cooldown() {
 	if c.counter > 0 {
 		c.counter -= 1
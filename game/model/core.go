@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"image"
 	"image/color"
 	"lintech/rego/game/loader"
 	"log"
@@ -20,6 +22,11 @@ type regoterInCore struct {
 	state  RegoterState
 	entity Entity
 	di     DrawInfo
+	done   <-chan struct{}
+	// nil for regoters that are plain channels
+	mailbox *mailbox
+	// ticks replaced by a newer one before the regoter handled them
+	skippedTicks int
 }
 
 var allRegoterEnum = [...]RegoterEnum{
@@ -34,18 +41,46 @@ var allRegoterEnum = [...]RegoterEnum{
 type Core struct {
 	Reactor
 	cfg GameCfg
-	rgs [len(allRegoterEnum)]map[ID]*regoterInCore
+	// only simulates, there is no camera, no textures and no window
+	headless bool
+	rgs      [len(allRegoterEnum)]map[ID]*regoterInCore
+	// regoters placed in the world by position
+	spatial *spatialHash
+	// topics of the world, every regoter is subscribed to TopicConfig
+	bus *Bus
+	// triggers in the order they were registered
+	triggers []*triggerInCore
+	// snapshots waiting for the state of regoters
+	snapshots []*snapshotInCore
+	// regoters removed by the last EventClearWorld, they may still send events
+	cleared map[ID]bool
+	// IDs of the regoters restored from a snapshot by their IDs in the snapshot
+	restoredIds map[ID]ID
+	// ticks so far and ticks skipped by regoters since the last report
+	ticks        int
+	skippedTicks int
 
 	// Camera
 	camera        *raycaster.Camera
 	scene         *ebiten.Image
 	tex           *loader.TextureHandler
 	debugMessages *stl4go.DList[string]
+	// textures of the level besides walls, floor and ceiling cells
+	groundTex      *image.RGBA
+	groundDebugTex *image.RGBA
+	floorTex       *ebiten.Image
+	skyTex         *ebiten.Image
+	// sky and ceiling drawn by drawCeiling when the level has ceilings
+	sky        *ebiten.Image
+	ceilingBuf *image.RGBA
+	ceilingImg *ebiten.Image
+	// the view ceilingImg was cast for
+	ceilingView ceilingView
 	//--array of levels, levels refer to "floors" of the world--//
-	mapObj       *loader.Map
-	collisionMap []geom.Line
-	mapWidth     int
-	mapHeight    int
+	mapObj    *loader.Map
+	manifest  *loader.Manifest
+	mapWidth  int
+	mapHeight int
 }
 
 func (g *Core) ProcessMessage(m ReactorEventMessage) error {
@@ -73,8 +108,44 @@ func (g *Core) ProcessMessage(m ReactorEventMessage) error {
 
 	case EventDamagePeer:
 		g.eventHandleDamage(m.sender, m.event.(EventDamagePeer))
+
+	case EventReloadContent:
+		g.eventHandleReloadContent(m.sender, m.event.(EventReloadContent))
+
+	case EventRegisterTrigger:
+		g.eventHandleRegisterTrigger(m.sender, m.event.(EventRegisterTrigger))
+
+	case EventUnregisterTrigger:
+		g.eventHandleUnregisterTrigger(m.sender, m.event.(EventUnregisterTrigger))
+
+	case EventSaveSnapshot:
+		g.eventHandleSaveSnapshot(m.sender, m.event.(EventSaveSnapshot))
+
+	case EventStateSaved:
+		g.eventHandleStateSaved(m.sender, m.event.(EventStateSaved))
+
+	case EventClearWorld:
+		g.eventHandleClearWorld(m.sender, m.event.(EventClearWorld))
+
+	case EventRestoreRegoter:
+		g.eventHandleRestoreRegoter(m.sender, m.event.(EventRestoreRegoter))
+
+	case EventAskCellFree:
+		g.eventHandleAskCellFree(m.sender, m.event.(EventAskCellFree))
+
+	case EventShutdown:
+		g.eventHandleShutdown(m.sender, m.event.(EventShutdown))
+
+	case EventReactorFailed:
+		g.eventHandleReactorFailed(m.sender, m.event.(EventReactorFailed))
+
+	case EventAskTickStats:
+		g.eventHandleAskTickStats(m.sender, m.event.(EventAskTickStats))
+
+	case EventAskBus:
+		g.eventHandleAskBus(m.sender, m.event.(EventAskBus))
 	default:
-		g.eventHandleUnknown(m.sender, m.event)
+		return g.eventHandleUnknown(m.sender, m.event)
 	}
 
 	return nil
@@ -91,13 +162,20 @@ func (g *Core) getPlayer() *regoterInCore {
 }
 
 func (g *Core) eventHandleGameEventTick(sender RcTx, e EventGameTick) {
+	g.ticks++
+	g.reportSkippedTicks()
+	// a headless Core may run without a player, e.g. on a server
 	player := g.getPlayer()
-	if player != nil {
+	if player != nil || g.headless {
+		var playerEntity Entity
+		if player != nil {
+			playerEntity = player.entity
+		}
 		for _, l := range g.rgs {
 			for _, v := range l {
 				m := ReactorEventMessage{g.tx,
-					EventUpdateTick{RgState: v.state, RgEntity: v.entity, PlayerEntity: player.entity}}
-				v.tx <- m
+					EventUpdateTick{RgState: v.state, RgEntity: v.entity, PlayerEntity: playerEntity}}
+				g.sendTick(v, m)
 			}
 		}
 	}
@@ -123,12 +201,7 @@ func (g *Core) eventHandleGameEventTick(sender RcTx, e EventGameTick) {
 func (g *Core) eventHandleGameEventCfgChanged(sender RcTx, e EventCfgChanged) {
 	if g.cfg != e.Cfg {
 		g.cfg = e.Cfg
-		for _, l := range g.rgs {
-			for _, v := range l {
-				e := ReactorEventMessage{g.tx, EventCfgChanged{Cfg: g.cfg}}
-				v.tx <- e
-			}
-		}
+		g.bus.Publish(g.tx, TopicConfig, EventCfgChanged{Cfg: g.cfg, Bus: g.bus})
 		// log.Print(fmt.Sprintf("current rg num %v", g.rgs.Len()))
 		g.applyConfig()
 	}
@@ -164,19 +237,37 @@ func createCoreSprite(rg *regoterInCore) *Sprite {
 
 func (g *Core) eventHandleRegisterRegoter(sender RcTx, e EventRegisterRegoter) {
 	d := e.RgData
-	rg := &regoterInCore{tx: sender, rgType: d.Entity.RgType, entity: d.Entity, di: d.DrawInfo}
+	rg := &regoterInCore{tx: sender, rgType: d.Entity.RgType, entity: d.Entity, di: d.DrawInfo, done: e.done,
+		mailbox: findMailbox(sender)}
 	if rg.di.AnimationRate != 0 && rg.di.SpriteIndex != 0 {
-		log.Fatal("This Regoter can not be both Animation and Sheet")
+		log.Printf("Warning: Regoter(%v) can not be both Animation and Sheet.", d.Entity.RgId)
+		g.rejectRegoter(sender)
+		return
 	}
-	if rg.di.Img == nil && d.Entity.RgType != RegoterEnumPlayer {
-		log.Fatal("Invalid nil Img for ", d.Entity.RgType, d.Entity.RgId)
+	if rg.di.Img == nil && d.Entity.RgType != RegoterEnumPlayer && !g.headless {
+		log.Printf("Warning: Invalid nil Img for Regoter(%v) of type %v.", d.Entity.RgId, d.Entity.RgType)
+		g.rejectRegoter(sender)
+		return
 	}
 	rg.state.AnimationRunning = true
-	rg.sprite = createCoreSprite(rg)
+	if !g.headless {
+		rg.sprite = createCoreSprite(rg)
+	}
 	g.rgs[rg.rgType][d.Entity.RgId] = rg
+	if spatialTypes[rg.rgType] {
+		g.spatial.insert(rg)
+	}
+	g.bus.Subscribe(TopicConfig, rg.tx)
 	// Send cfg to newly registered Regoter
-	m := ReactorEventMessage{g.tx, EventCfgChanged{Cfg: g.cfg}}
-	rg.tx <- m
+	m := ReactorEventMessage{g.tx, EventCfgChanged{Cfg: g.cfg, Bus: g.bus}}
+	send(rg.tx, m)
+	// it knows its config before it hears of the triggers it is in
+	g.updateTriggers(rg, rg.entity.Position)
+}
+
+// rejectRegoter stops a regoter Core did not register.
+func (g *Core) rejectRegoter(tx RcTx) {
+	send(tx, ReactorEventMessage{g.tx, EventUnregisterConfirmed{}})
 }
 
 // func (g *Core) eventHandleUpdatedImg(e RegoterEventUpdatedImg) {
@@ -186,9 +277,8 @@ func (g *Core) eventHandleRegisterRegoter(sender RcTx, e EventRegisterRegoter) {
 
 func (g *Core) findRegoter(id ID) (*regoterInCore, bool) {
 	if id == NULL_ID {
-		log.Fatalf("ID can not be NULL_ID(%v).", NULL_ID)
-	}
-	if id == WALL_ID {
+		log.Printf("Warning: ID can not be NULL_ID(%v).", NULL_ID)
+	} else if id == WALL_ID {
 		log.Printf("Info: Try to find WALL_ID(%v) in core", WALL_ID)
 	} else {
 		for _, l := range g.rgs {
@@ -203,7 +293,14 @@ func (g *Core) findRegoter(id ID) (*regoterInCore, bool) {
 
 func (g *Core) eventHandleMovement(sender RcTx, e EventMovement) {
 	if p, ok := g.findRegoter(e.RgId); ok {
+		start := p.entity.Position
 		moved := g.updatedMove(p, sender, e)
+		if moved && spatialTypes[p.rgType] {
+			g.spatial.update(p)
+		}
+		if moved {
+			g.updateTriggers(p, start)
+		}
 		if moved && (p.rgType == RegoterEnumPlayer) {
 			g.updatePlayerCamera(&p.entity, moved, false)
 		}
@@ -213,25 +310,41 @@ func (g *Core) eventHandleMovement(sender RcTx, e EventMovement) {
 		if e.Command.StartAnimation {
 			p.state.AnimationRunning = true
 		}
-	} else {
-		log.Fatalf("Error: Can not find Regoter(%v) in Event(%T).", e.RgId, e)
+	} else if !g.cleared[e.RgId] {
+		log.Printf("Warning: Can not find Regoter(%v) in Event(%T).", e.RgId, e)
 	}
 }
 
 func (g *Core) eventHandleDamage(sender RcTx, e EventDamagePeer) {
 	if e.peer == NULL_ID {
-		log.Fatalf("ID can not be NULL_ID(%v).", NULL_ID)
+		log.Printf("Warning: ID can not be NULL_ID(%v).", NULL_ID)
+		return
 	}
 	if e.peer != WALL_ID {
 		if p, ok := g.findRegoter(e.peer); ok {
 			m := ReactorEventMessage{g.tx, EventHealthChange{change: e.damage}}
-			p.tx <- m
+			send(p.tx, m)
 		} else {
 			log.Printf("Warning: Can not find Regoter(%v) in Event(%T).", e.peer, e)
 		}
 	}
 }
 
+func (g *Core) eventHandleAskCellFree(sender RcTx, e EventAskCellFree) {
+	free := !g.mapObj.WallAt(e.X, e.Y)
+	if free {
+		minX, minY := float64(e.X), float64(e.Y)
+		g.spatial.queryRect(minX, minY, minX+1, minY+1, func(rg *regoterInCore) {
+			p := rg.entity.Position
+			if rg.entity.CollisionRadius > 0 &&
+				p.X >= minX && p.X < minX+1 && p.Y >= minY && p.Y < minY+1 {
+				free = false
+			}
+		})
+	}
+	send(sender, ReactorEventMessage{g.tx, EventCellFree{Free: free}})
+}
+
 func (g *Core) updatedMove(p *regoterInCore, sender RcTx, e EventMovement) bool {
 	pe := &p.entity
 	rgType := pe.RgType
@@ -257,30 +370,34 @@ func (g *Core) updatedMove(p *regoterInCore, sender RcTx, e EventMovement) bool
 			checkAlternate = true
 		}
 
-		newPos, collisionEntity := g.getValidMove(pe, lineEnd.X, lineEnd.Y, lineEnd.Z, checkAlternate)
-		if collisionEntity != nil {
-			// Send EventCollistion to both Entities in collistion
-			sender <- ReactorEventMessage{
-				g.tx, EventCollision{collistion: *collisionEntity}}
-
-			peerId := collisionEntity.peer
-			if peerId != WALL_ID {
-				if rg, ok := g.findRegoter(peerId); ok {
-					collisionForPeer := EntityCollision{peer: pe.RgId, distance: collisionEntity.distance,
-						position: collisionEntity.position}
-					rg.tx <- ReactorEventMessage{
-						g.tx, EventCollision{collistion: collisionForPeer}}
+		newPos, contacts := g.getValidMove(pe, lineEnd.X, lineEnd.Y, lineEnd.Z, checkAlternate)
+		if len(contacts) > 0 {
+			// Send all contacts to the moving Entity and its contact to each peer it touches,
+			// so a piercing or area hit reaches every peer on the way
+			send(sender, ReactorEventMessage{
+				g.tx, EventCollision{contacts: contacts}})
+
+			for _, contact := range contacts {
+				if contact.peer == WALL_ID {
+					continue
+				}
+				if rg, ok := g.findRegoter(contact.peer); ok {
+					collisionForPeer := EntityCollision{peer: pe.RgId, distance: contact.distance,
+						position: contact.position,
+						normal:   geom3d.Vector3{X: -contact.normal.X, Y: -contact.normal.Y, Z: -contact.normal.Z}}
+					send(rg.tx, ReactorEventMessage{
+						g.tx, EventCollision{contacts: []EntityCollision{collisionForPeer}}})
 				} else {
-					log.Printf("Warning: Can not find Peer Regoter(%v) in Event(%T).", peerId, e)
+					log.Printf("Warning: Can not find Peer Regoter(%v) in Event(%T).", contact.peer, e)
 				}
 			}
 		} else {
 			if lineEnd.Z < -1 {
 				// Hit ground
 				collision := EntityCollision{peer: WALL_ID, distance: 0,
-					position: *lineEnd}
-				sender <- ReactorEventMessage{
-					g.tx, EventCollision{collistion: collision}}
+					position: *lineEnd, normal: geom3d.Vector3{Z: 1}}
+				send(sender, ReactorEventMessage{
+					g.tx, EventCollision{contacts: []EntityCollision{collision}}})
 			}
 		}
 
@@ -320,61 +437,178 @@ func limitVelocity(velocity float64, max float64) float64 {
 
 func (g *Core) eventHandleRegoterUnregister(sender RcTx, e EventUnregisterRegoter) {
 	if e.RgId == NULL_ID || e.RgId == WALL_ID {
-		log.Fatalf("ID can not unregister invalide ID(%v).", e.RgId)
+		log.Printf("Warning: ID can not unregister invalide ID(%v).", e.RgId)
+		return
 	}
 	// log.Printf("Unregister Regoter %v", e.RgId)
 	for _, l := range g.rgs {
 		if v, ok := l[e.RgId]; ok {
-			m := ReactorEventMessage{g.tx, EventUnregisterConfirmed{}}
-			v.tx <- m
-			delete(l, e.RgId)
+			g.removeRegoter(l, v)
 		}
 	}
 }
 
+// removeRegoter removes rg from its layer l of Core and confirms it to rg.
+func (g *Core) removeRegoter(l map[ID]*regoterInCore, rg *regoterInCore) {
+	m := ReactorEventMessage{g.tx, EventUnregisterConfirmed{}}
+	send(rg.tx, m)
+	delete(l, rg.entity.RgId)
+	g.spatial.remove(rg.entity.RgId)
+	g.removeFromTriggers(rg)
+	g.removeFromSnapshots(rg.entity.RgId)
+	g.bus.UnsubscribeAll(rg.tx)
+}
+
 func (g *Core) eventHandleUnknown(sender RcTx, e IReactorEvent) error {
-	log.Fatalf("Unknown event: %T", e)
-	return nil
+	return fmt.Errorf("unknown event %T", e)
 }
 
-func NewCore(cfg GameCfg) RcTx {
-	rc := NewReactorCore()
+func (g *Core) eventHandleReloadContent(sender RcTx, e EventReloadContent) {
+	if g.headless {
+		g.setMap(e.Map)
+		g.placeRegoters()
+		log.Printf("Reloaded level %q", g.mapObj.Name())
+		return
+	}
+	if err := g.loadContent(e.Map, e.Manifest); err != nil {
+		log.Printf("Warning: Reload failed, keeping level %q: %v", g.mapObj.Name(), err)
+		return
+	}
+	// regoters stay where they are if the new level has room for them
+	g.placeRegoters()
+	g.applyConfig()
+	if player := g.getPlayer(); player != nil {
+		g.updatePlayerCamera(&player.entity, false, true)
+	}
+	log.Printf("Reloaded level %q", g.mapObj.Name())
+}
+
+// placeRegoters checks the regoters placed in the world against a changed level.
+// The player is moved to the player start if it is outside the level or in a wall,
+// other regoters there are unregistered.
+func (g *Core) placeRegoters() {
+	for t, l := range g.rgs {
+		if !spatialTypes[t] {
+			continue
+		}
+		for _, rg := range l {
+			p := rg.entity.Position
+			if !g.mapObj.WallAt(int(math.Floor(p.X)), int(math.Floor(p.Y))) {
+				continue
+			}
+			if rg.rgType != RegoterEnumPlayer {
+				log.Printf("Warning: Regoter(%v) at (%.2f, %.2f) is outside the free cells of level %q, unregistered.",
+					rg.entity.RgId, p.X, p.Y, g.mapObj.Name())
+				g.removeRegoter(l, rg)
+				g.cleared[rg.entity.RgId] = true
+				continue
+			}
+			start := g.mapObj.PlayerStart()
+			log.Printf("Warning: Player at (%.2f, %.2f) is outside the free cells of level %q, moved to (%.2f, %.2f).",
+				p.X, p.Y, g.mapObj.Name(), start.X, start.Y)
+			rg.entity.Position = Position{X: start.X, Y: start.Y, Z: p.Z}
+			g.spatial.update(rg)
+			// a jump, not a move through the triggers on the way
+			g.updateTriggers(rg, rg.entity.Position)
+		}
+	}
+}
+
+func NewCore(cfg GameCfg, mapObj *loader.Map, manifest *loader.Manifest) RcTx {
+	core := newCore(cfg)
+	if err := core.loadContent(mapObj, manifest); err != nil {
+		log.Fatal(err)
+	}
+
+	core.applyConfig()
+
+	core.Reactor.Start(core)
+	return core.tx
+}
+
+// NewHeadlessCore returns a Core that only simulates the level: registration,
+// ticks, movement, collision and damage. It draws nothing and needs no GPU or
+// window, so it runs on servers and in tests. It is driven by EventGameTick,
+// EventDraw is answered without drawing.
+func NewHeadlessCore(cfg GameCfg, mapObj *loader.Map) RcTx {
+	core := newCore(cfg)
+	core.headless = true
+	core.setMap(mapObj)
+
+	core.Reactor.Start(core)
+	return core.tx
+}
+
+func newCore(cfg GameCfg) *Core {
+	rc := NewWorld(cfg).newReactorCore()
+	rc.Supervise(nil, PolicyEscalate)
 	var rgs [len(allRegoterEnum)]map[ID]*regoterInCore
 	for i := 0; i < len(rgs); i++ {
 		rgs[i] = map[ID]*regoterInCore{}
 	}
 
-	// load map
-	mapObj := loader.NewMap()
-	collisionMap := mapObj.GetCollisionLines(loader.ClipDistance)
-	tex := loader.LoadContent(mapObj)
-
-	worldMap := mapObj.Level(0)
-	mapWidth := len(worldMap)
-	mapHeight := len(worldMap[0])
-
 	debugMessages := stl4go.NewDList[string]()
-	core := &Core{Reactor: rc, rgs: rgs,
-		mapObj: mapObj, collisionMap: collisionMap,
-		mapWidth: mapWidth, mapHeight: mapHeight,
-		debugMessages: debugMessages, tex: tex, cfg: cfg,
+	g := &Core{Reactor: rc, rgs: rgs, spatial: newSpatialHash(), bus: NewBus(),
+		cleared: map[ID]bool{}, restoredIds: map[ID]ID{},
+		debugMessages: debugMessages, cfg: cfg,
 	}
+	g.world.core = &g.Reactor
+	return g
+}
 
-	core.applyConfig()
+// loadContent loads the textures of the level and swaps in the level and its textures.
+// The current ones are kept if loading fails.
+func (g *Core) loadContent(mapObj *loader.Map, manifest *loader.Manifest) error {
+	tex, err := loader.LoadContent(mapObj, manifest)
+	if err != nil {
+		return err
+	}
+	groundTex, err := manifest.TextureRGBA("grass")
+	if err != nil {
+		return err
+	}
+	groundDebugTex, err := manifest.TextureRGBA("grass_debug")
+	if err != nil {
+		return err
+	}
+	floorTex, err := manifest.Texture("floor")
+	if err != nil {
+		return err
+	}
+	skyTex, err := manifest.Texture("sky")
+	if err != nil {
+		return err
+	}
 
-	go core.Reactor.Run(core)
-	return core.tx
+	g.setMap(mapObj)
+	g.manifest = manifest
+	g.tex = tex
+	g.groundTex, g.groundDebugTex = groundTex, groundDebugTex
+	g.floorTex, g.skyTex = floorTex, skyTex
+	return nil
+}
+
+// setMap swaps in the level the simulation runs in.
+func (g *Core) setMap(mapObj *loader.Map) {
+	worldMap := mapObj.Level(0)
+	g.mapObj = mapObj
+	g.mapWidth = len(worldMap)
+	g.mapHeight = len(worldMap[0])
 }
+
 func (core *Core) applyConfig() {
+	if core.headless {
+		return
+	}
 	cfg := core.cfg
 	//--init camera and renderer--//
 	// use scale to keep the desired window width and height
 	// load content once when first run
 	// load texture handler
 	if cfg.Debug {
-		core.tex.FloorTex = loader.GetRGBAFromFile("grass_debug.png")
+		core.tex.FloorTex = core.groundDebugTex
 	} else {
-		core.tex.FloorTex = loader.GetRGBAFromFile("grass.png")
+		core.tex.FloorTex = core.groundTex
 	}
 
 	core.camera = raycaster.NewCamera(cfg.Width, cfg.Height, loader.TexWidth,
@@ -387,8 +621,14 @@ func (core *Core) applyConfig() {
 
 	core.setRenderDistance(cfg.RenderDistance)
 
-	core.camera.SetFloorTexture(loader.GetTextureFromFile("floor.png"))
-	core.camera.SetSkyTexture(loader.GetTextureFromFile("sky.png"))
+	core.setRenderFloorTexture(cfg.RenderFloorTex)
+	core.camera.SetFloorTexture(core.floorTex)
+	core.sky = nil
+	if core.mapObj.HasCeiling() {
+		core.sky = core.skyTex
+	} else {
+		core.camera.SetSkyTexture(core.skyTex)
+	}
 
 	core.setFovAngle(cfg.FovDegrees)
 	core.cfg.FovDepth = core.camera.FovDepth()
@@ -406,7 +646,7 @@ func (core *Core) applyConfig() {
 
 // Update camera to match player position and orientation
 func (g *Core) updatePlayerCamera(pe *Entity, moved bool, forceUpdate bool) {
-	if !moved && !forceUpdate {
+	if (!moved && !forceUpdate) || g.headless {
 		// only update camera position if player moved or forceUpdate set
 		return
 	}
@@ -437,6 +677,7 @@ func (g *Core) setRenderScale(renderScale float64) {
 		g.camera.SetViewSize(g.cfg.Width, g.cfg.Height)
 	}
 	g.scene = ebiten.NewImage(g.cfg.Width, g.cfg.Height)
+	g.setCeilingSize(g.cfg.Width, g.cfg.Height)
 }
 
 func (g *Core) setRenderDistance(renderDistance float64) {
@@ -471,8 +712,8 @@ func (g *Core) setFovAngle(fovDegrees float64) {
 }
 
 func (g *Core) setRenderFloorTexture(r bool) {
-	//Todo
-	//g.tex.RenderFloorTex = r
+	g.cfg.RenderFloorTex = r
+	g.tex.RenderFloorTex = r
 }
 
 func simplifyAngle(angle float64) float64 {
@@ -11,10 +11,12 @@ import (
 
 func (g *Core) eventHandleGameEventDraw(sender RcTx, e EventDraw) {
 
-	g.drawScreen(e.Screen)
+	if !g.headless {
+		g.drawScreen(e.Screen)
+	}
 
 	m := ReactorEventMessage{g.tx, EventDrawDone{}}
-	sender <- m
+	send(sender, m)
 
 }
 
@@ -45,6 +47,7 @@ func (g *Core) drawScreen(screen *ebiten.Image) {
 	g.camera.Update(raycastSprites)
 
 	// Render raycast scene
+	g.drawCeiling(g.scene)
 	g.camera.Draw(g.scene)
 
 	// draw equipped weapon
@@ -2,8 +2,6 @@ package model
 
 import (
 	"image/color"
-	"lintech/rego/game/loader"
-	"log"
 
 	"github.com/harbdog/raycaster-go"
 )
@@ -14,24 +12,10 @@ type Crosshairs struct {
 	cfg    GameCfg
 }
 
-func (r *Crosshairs) ProcessMessage(m ReactorEventMessage) error {
-	// log.Print(fmt.Sprintf("(%v) recv %T", r.thing.GetData().Entity.RgId, e))
-	switch m.event.(type) {
-	case EventUpdateTick:
-		r.eventHandleUpdateTick(m.sender, m.event.(EventUpdateTick))
-	case EventUnregisterConfirmed:
-		r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-	case EventCfgChanged:
-		r.eventHandleCfgChanged(m.sender, m.event.(EventCfgChanged))
-	default:
-		r.eventHandleUnknown(m.sender, m.event)
-	}
-	return nil
-}
-
-func (r *Crosshairs) eventHandleUnknown(sender RcTx, e IReactorEvent) error {
-	log.Fatalf("Unknown event: %T", e)
-	return nil
+// registerHandlers declares the events the Crosshairs handles.
+func (r *Crosshairs) registerHandlers() {
+	On(&r.Reactor, r.eventHandleUpdateTick)
+	On(&r.Reactor, r.eventHandleCfgChanged)
 }
 
 func (r *Crosshairs) eventHandleCfgChanged(sender RcTx, e EventCfgChanged) {
@@ -51,25 +35,29 @@ func NewCrosshairs(coreTx RcTx) RcTx {
 		CollisionRadius: 0,
 		CollisionHeight: 0,
 	}
+	sheet := loadSprite("crosshairs")
 	di := DrawInfo{
 		ImgLayer:    ImgLayerSprite,
-		Img:         loader.GetSpriteFromFile("crosshairs_sheet.png"),
-		Columns:     8,
-		Rows:        8,
+		Asset:       "crosshairs",
+		Img:         sheet.Img,
+		Columns:     sheet.Columns,
+		Rows:        sheet.Rows,
 		SpriteIndex: 55,
 		HitIndex:    57,
 	}
 	t := &Crosshairs{
-		Reactor: NewReactor(),
+		Reactor: WorldOf(coreTx).NewReactor(),
 		rgData: RegoterData{
 			Entity:   entity,
 			DrawInfo: di,
 		},
 	}
 
-	go t.Reactor.Run(t)
-	m := ReactorEventMessage{t.tx, EventRegisterRegoter{t.tx, t.rgData}}
-	coreTx <- m
+	t.Supervise(coreTx, PolicyRestart)
+	t.registerHandlers()
+	t.Reactor.Start(t)
+	m := ReactorEventMessage{t.tx, EventRegisterRegoter{t.tx, t.rgData, t.done}}
+	send(coreTx, m)
 	return t.tx
 }
 
@@ -86,9 +74,5 @@ func NewCrosshairs(coreTx RcTx) RcTx {
 func (c *Crosshairs) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 }
 
-func (c *Crosshairs) eventHandleUnregisterConfirmed(sender RcTx, e EventUnregisterConfirmed) {
-	c.running = false
-}
-
 func (c *Crosshairs) SetConfig(cfg GameCfg) {
 }
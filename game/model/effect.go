@@ -2,8 +2,6 @@ package model
 
 import (
 	"image/color"
-	"lintech/rego/game/loader"
-	"log"
 
 	"github.com/harbdog/raycaster-go"
 )
@@ -11,8 +9,7 @@ import (
 type Effect struct {
 	Reactor
 	EffectTemplate
-	cfg          GameCfg
-	unregistered bool
+	cfg GameCfg
 }
 
 type EffectTemplate struct {
@@ -20,31 +17,20 @@ type EffectTemplate struct {
 	LoopCount int
 }
 
-func (r *Effect) ProcessMessage(m ReactorEventMessage) error {
-	// log.Print(fmt.Sprintf("(%v) recv %T", r.thing.GetData().Entity.RgId, e))
-	switch m.event.(type) {
-	case EventUnregisterConfirmed:
-		r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-	default:
-		if !r.unregistered {
-			switch m.event.(type) {
-			case EventUpdateTick:
-				r.eventHandleUpdateTick(m.sender, m.event.(EventUpdateTick))
-			case EventUnregisterConfirmed:
-				r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-			case EventCfgChanged:
-				r.eventHandleCfgChanged(m.sender, m.event.(EventCfgChanged))
-			default:
-				r.eventHandleUnknown(m.sender, m.event)
-			}
-		}
-	}
-	return nil
+// registerHandlers declares the events the Effect handles.
+func (r *Effect) registerHandlers() {
+	On(&r.Reactor, r.eventHandleUpdateTick)
+	On(&r.Reactor, r.eventHandleCfgChanged)
+	On(&r.Reactor, r.eventHandleSaveState)
+	On(&r.Reactor, r.eventHandleRestoreState)
+}
+
+// an effect only plays its animation, Core keeps the state of it
+func (r *Effect) eventHandleSaveState(sender RcTx, e EventSaveState) {
+	send(sender, ReactorEventMessage{r.tx, EventStateSaved{RgId: r.rgData.Entity.RgId}})
 }
 
-func (r *Effect) eventHandleUnknown(sender RcTx, e IReactorEvent) error {
-	log.Fatalf("Unknown event: %T", e)
-	return nil
+func (r *Effect) eventHandleRestoreState(sender RcTx, e EventRestoreState) {
 }
 
 func NewEffectTemplate(di DrawInfo, scale float64, loopCount int) *EffectTemplate {
@@ -72,22 +58,26 @@ func NewEffectTemplate(di DrawInfo, scale float64, loopCount int) *EffectTemplat
 }
 
 func NewRedExplosionEffect() *EffectTemplate {
+	sheet := loadSprite("red_explosion")
 	di := DrawInfo{
-		Img:           loader.GetSpriteFromFile("red_explosion_sheet.png"),
+		Asset:         "red_explosion",
+		Img:           sheet.Img,
 		AnimationRate: 1,
-		Columns:       8,
-		Rows:          3,
+		Columns:       sheet.Columns,
+		Rows:          sheet.Rows,
 	}
 	redExplosionEffect := NewEffectTemplate(di, 0.20, 1)
 	return redExplosionEffect
 }
 
 func NewBlueExplosionEffect() *EffectTemplate {
+	sheet := loadSprite("blue_explosion")
 	di := DrawInfo{
-		Img:           loader.GetSpriteFromFile("blue_explosion_sheet.png"),
+		Asset:         "blue_explosion",
+		Img:           sheet.Img,
 		AnimationRate: 3,
-		Columns:       5,
-		Rows:          3,
+		Columns:       sheet.Columns,
+		Rows:          sheet.Rows,
 	}
 	blueExplosionEffect := NewEffectTemplate(di, 0.75, 1)
 	return blueExplosionEffect
@@ -96,27 +86,25 @@ func NewBlueExplosionEffect() *EffectTemplate {
 func (ef *Effect) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 	if e.RgState.AnimationLoopCnt >= ef.LoopCount {
 		m := ReactorEventMessage{ef.tx, EventUnregisterRegoter{RgId: ef.rgData.Entity.RgId}}
-		sender <- m
-		ef.unregistered = true
+		send(sender, m)
+		ef.Unregistering()
 	}
 }
 
-func (ef *Effect) eventHandleUnregisterConfirmed(sender RcTx, e EventUnregisterConfirmed) {
-	ef.running = false
-}
-
 func NewEffect(coreTx RcTx, et *EffectTemplate, position Position) RcTx {
 	ef := &Effect{
-		Reactor:        NewReactor(),
+		Reactor:        WorldOf(coreTx).NewReactor(),
 		EffectTemplate: *et,
 	}
 	// Don't use ID of Template
 	ef.rgData.Entity.RgId = <-IdGen
 	ef.rgData.Entity.Position = position
 	//
-	go ef.Reactor.Run(ef)
-	m := ReactorEventMessage{ef.tx, EventRegisterRegoter{ef.tx, ef.rgData}}
-	coreTx <- m
+	ef.Supervise(coreTx, PolicyUnregister)
+	ef.registerHandlers()
+	ef.Reactor.Start(ef)
+	m := ReactorEventMessage{ef.tx, EventRegisterRegoter{ef.tx, ef.rgData, ef.done}}
+	send(coreTx, m)
 	return ef.tx
 }
 
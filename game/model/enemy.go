@@ -1,9 +1,7 @@
 package model
 
 import (
-	"lintech/rego/game/loader"
-	"log"
-	"math/rand"
+	"fmt"
 
 	"github.com/harbdog/raycaster-go"
 	"github.com/harbdog/raycaster-go/geom"
@@ -15,65 +13,72 @@ type Enemy struct {
 	Reactor
 	rgData           RegoterData
 	cfg              GameCfg
-	unregistered     bool
+	bus              *Bus
 	health           int
 	collistionRotate float64
 	harm             int
 	audioPlayer      *RegoAudioPlayer
 }
 
-func (r *Enemy) ProcessMessage(m ReactorEventMessage) error {
-	// log.Print(fmt.Sprintf("(%v) recv %T", r.thing.GetData().Entity.RgId, e))
-	switch m.event.(type) {
-	case EventUnregisterConfirmed:
-		r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-	default:
-		if !r.unregistered {
-			switch m.event.(type) {
-			case EventCollision:
-				r.eventHandleCollision(m.sender, m.event.(EventCollision))
-			case EventHealthChange:
-				r.eventHandleHealthChange(m.sender, m.event.(EventHealthChange))
-			case EventUpdateTick:
-				r.eventHandleUpdateTick(m.sender, m.event.(EventUpdateTick))
-			case EventCfgChanged:
-				r.eventHandleCfgChanged(m.sender, m.event.(EventCfgChanged))
-			default:
-				r.eventHandleUnknown(m.sender, m.event)
-			}
-		}
-	}
-	return nil
-}
-
-func (r *Enemy) eventHandleUnknown(sender RcTx, e IReactorEvent) error {
-	log.Fatalf("Unknown event: %T", e)
-	return nil
+// registerHandlers declares the events the Enemy handles.
+func (r *Enemy) registerHandlers() {
+	OnFallible(&r.Reactor, r.eventHandleCollision)
+	On(&r.Reactor, r.eventHandleHealthChange)
+	On(&r.Reactor, r.eventHandleTriggerEnter)
+	On(&r.Reactor, r.eventHandleTriggerExit)
+	On(&r.Reactor, r.eventHandleUpdateTick)
+	On(&r.Reactor, r.eventHandleCfgChanged)
+	On(&r.Reactor, r.eventHandleAskHealth)
+	On(&r.Reactor, r.eventHandleSaveState)
+	On(&r.Reactor, r.eventHandleRestoreState)
 }
 
 func (r *Enemy) eventHandleCfgChanged(sender RcTx, e EventCfgChanged) {
 	r.cfg = e.Cfg
+	r.bus = e.Bus
+}
+
+func (r *Enemy) eventHandleAskHealth(sender RcTx, e EventAskHealth) {
+	send(sender, ReactorEventMessage{r.tx, EventHealth{Health: r.health}})
+}
+
+func (r *Enemy) eventHandleSaveState(sender RcTx, e EventSaveState) {
+	state := SavedState{Health: r.health}
+	send(sender, ReactorEventMessage{r.tx, EventStateSaved{RgId: r.rgData.Entity.RgId, State: state}})
+}
+
+func (r *Enemy) eventHandleRestoreState(sender RcTx, e EventRestoreState) {
+	r.health = e.State.Health
+}
+
+func (r *Enemy) eventHandleTriggerEnter(sender RcTx, e EventTriggerEnter) {
+}
+
+func (r *Enemy) eventHandleTriggerExit(sender RcTx, e EventTriggerExit) {
 }
 
 func (r *Enemy) eventHandleHealthChange(sender RcTx, e EventHealthChange) {
 	r.health -= e.change
 	if r.health < 0 {
 		m := ReactorEventMessage{r.tx, EventUnregisterRegoter{RgId: r.rgData.Entity.RgId}}
-		sender <- m
-		r.unregistered = true
+		send(sender, m)
+		r.Unregistering()
+		r.bus.Publish(r.tx, TopicEnemyDied, EventEnemyDied{RgId: r.rgData.Entity.RgId})
 	}
 
 }
 
-func (c *Enemy) eventHandleCollision(sender RcTx, e EventCollision) {
-	if e.collistion.peer == NULL_ID {
-		log.Fatalf("Info: Try to find NULL_ID(%v) in core", NULL_ID)
+func (c *Enemy) eventHandleCollision(sender RcTx, e EventCollision) error {
+	contact := e.nearest()
+	if contact.peer == NULL_ID {
+		return fmt.Errorf("collision with NULL_ID(%v)", NULL_ID)
 	}
-	if e.collistion.peer != WALL_ID && c.harm != 0 {
-		m := ReactorEventMessage{c.tx, EventDamagePeer{peer: e.collistion.peer, damage: c.harm}}
-		sender <- m
+	if contact.peer != WALL_ID && c.harm != 0 {
+		m := ReactorEventMessage{c.tx, EventDamagePeer{peer: contact.peer, damage: c.harm}}
+		send(sender, m)
 	}
-	c.collistionRotate = rand.Float64() * geom.Pi2
+	c.collistionRotate = c.world.randFloat64() * geom.Pi2
+	return nil
 }
 
 func NewEnemy(coreTx RcTx,
@@ -92,11 +97,13 @@ func NewEnemy(coreTx RcTx,
 		Anchor:          anchor,
 		CollisionRadius: cp.CollisionRadius,
 		CollisionHeight: cp.CollisionHeight,
+		CollisionLayer:  cp.CollisionLayer,
+		CollisionMask:   cp.CollisionMask,
 		Velocity:        velocity,
-		Angle:           rand.Float64() * geom.Pi2,
+		Angle:           WorldOf(coreTx).randFloat64() * geom.Pi2,
 	}
 	t := &Enemy{
-		Reactor: NewReactor(),
+		Reactor: WorldOf(coreTx).NewReactor(),
 		rgData: RegoterData{
 			Entity:   entity,
 			DrawInfo: di,
@@ -106,9 +113,11 @@ func NewEnemy(coreTx RcTx,
 		audioPlayer: audioPlayer,
 	}
 
-	go t.Reactor.Run(t)
-	m := ReactorEventMessage{t.tx, EventRegisterRegoter{t.tx, t.rgData}}
-	coreTx <- m
+	t.Supervise(coreTx, PolicyUnregister)
+	t.registerHandlers()
+	t.Reactor.Start(t)
+	m := ReactorEventMessage{t.tx, EventRegisterRegoter{t.tx, t.rgData, t.done}}
+	send(coreTx, m)
 	return t.tx
 }
 
@@ -133,15 +142,11 @@ func (c *Enemy) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 	if isMoving(movement) {
 		v := EventMovement{RgId: c.rgData.Entity.RgId, Move: movement}
 		m := ReactorEventMessage{c.tx, v}
-		sender <- m
+		send(sender, m)
 		c.playAudio(e)
 	}
 }
 
-func (c *Enemy) eventHandleUnregisterConfirmed(sender RcTx, e EventUnregisterConfirmed) {
-	c.running = false
-}
-
 func (c *Enemy) SetConfig(cfg GameCfg) {
 }
 
@@ -151,23 +156,34 @@ func (c *Enemy) GetData() RegoterData {
 
 var cnt = 1
 
-func NewSorcerer(conrTx RcTx) {
-	sorcImg := loader.GetSpriteFromFile("sorcerer_sheet.png")
+// enemies run into walls, the player and each other
+const enemyCollisionMask = CollisionLayerWall | CollisionLayerPlayer | CollisionLayerEnemy
+
+// The enemy constructors below place the enemy at po,
+// or line them up in the demo area when po is nil.
+
+func NewSorcerer(conrTx RcTx, po *Position) RcTx {
+	sorcSheet := loadSprite("sorcerer")
+	sorcImg := sorcSheet.Img
 	sorcWidth, sorcHeight := sorcImg.Bounds().Dx(), sorcImg.Bounds().Dy()
-	sorcCols, sorcRows := 10, 1
+	sorcCols, sorcRows := sorcSheet.Columns, sorcSheet.Rows
 	sorcScale := 1.0
 	sorcVelocity := 0.02
 	// in pixels, radius and height to use for collision testing
 	sorcPxRadius, sorcPxHeight := 40.0, 120.0
 	collisionRadius := (sorcScale * sorcPxRadius) / (float64(sorcWidth) / float64(sorcCols))
 	collisionHeight := (sorcScale * sorcPxHeight) / (float64(sorcHeight) / float64(sorcRows))
-	cnt += 1
-	y := float64(2+cnt/100) * collisionRadius * 4
-	x := float64(2+cnt%100) * collisionRadius * 4
+	if po == nil {
+		cnt += 1
+		y := float64(2+cnt/100) * collisionRadius * 4
+		x := float64(2+cnt%100) * collisionRadius * 4
+		po = &Position{X: x, Y: y, Z: 0}
+	}
 
-	NewEnemy(conrTx,
-		Position{X: x, Y: y, Z: 0},
+	return NewEnemy(conrTx,
+		*po,
 		DrawInfo{
+			Asset:             "sorcerer",
 			Img:               sorcImg,
 			ImgLayer:          ImgLayerSprite,
 			Columns:           sorcCols,
@@ -179,6 +195,8 @@ func NewSorcerer(conrTx RcTx) {
 		CollisionSpace{
 			CollisionRadius: collisionRadius,
 			CollisionHeight: collisionHeight,
+			CollisionLayer:  CollisionLayerEnemy,
+			CollisionMask:   enemyCollisionMask,
 		},
 		sorcVelocity,
 		10,
@@ -189,7 +207,7 @@ func NewSorcerer(conrTx RcTx) {
 
 }
 
-func NewWalker(coreTx RcTx) {
+func NewWalker(coreTx RcTx, po *Position) RcTx {
 	// animated walking 8-directional sprite character
 	// [walkerTexFacingMap] player facing angle : texture row index
 	var walkerTexFacingMap = map[float64]int{
@@ -202,9 +220,10 @@ func NewWalker(coreTx RcTx) {
 		geom.Radians(45):  6,
 		geom.Radians(0):   7,
 	}
-	walkerImg := loader.GetSpriteFromFile("outleader_walking_sheet.png")
+	walkerSheet := loadSprite("walker")
+	walkerImg := walkerSheet.Img
 	walkerWidth, walkerHeight := walkerImg.Bounds().Dx(), walkerImg.Bounds().Dy()
-	walkerCols, walkerRows := 4, 8
+	walkerCols, walkerRows := walkerSheet.Columns, walkerSheet.Rows
 	walkerScale := 0.75
 	// in pixels, radius and height to use for collision testing
 	walkerPxRadius, walkerPxHeight := 30.0, 80.0
@@ -214,13 +233,17 @@ func NewWalker(coreTx RcTx) {
 	// give sprite a sample velocity for movement
 	walkerVelocity := 0.02
 
-	cnt += 1
-	y := float64(2+cnt/100)*walkerCollisionRadius*4 + walkerCollisionRadius*4
-	x := float64(2+cnt%100) * walkerCollisionRadius * 4
+	if po == nil {
+		cnt += 1
+		y := float64(2+cnt/100)*walkerCollisionRadius*4 + walkerCollisionRadius*4
+		x := float64(2+cnt%100) * walkerCollisionRadius * 4
+		po = &Position{X: x, Y: y, Z: 0}
+	}
 
-	NewEnemy(coreTx,
-		Position{X: x, Y: y, Z: 0},
+	return NewEnemy(coreTx,
+		*po,
 		DrawInfo{
+			Asset:             "walker",
 			Img:               walkerImg,
 			ImgLayer:          ImgLayerSprite,
 			Columns:           walkerCols,
@@ -233,6 +256,8 @@ func NewWalker(coreTx RcTx) {
 		CollisionSpace{
 			CollisionRadius: walkerCollisionRadius,
 			CollisionHeight: walkerCollisionHeight,
+			CollisionLayer:  CollisionLayerEnemy,
+			CollisionMask:   enemyCollisionMask,
 		},
 		walkerVelocity,
 		5,
@@ -243,7 +268,7 @@ func NewWalker(coreTx RcTx) {
 	// log.Printf("%v, %v", walkerCollisionRadius, walkerCollisionHeight)
 }
 
-func NewBat(coreTx RcTx) {
+func NewBat(coreTx RcTx, po *Position) RcTx {
 	// animated flying 4-directional sprite creature
 	// [batTexFacingMap] player facing angle : texture row index
 	var batTexFacingMap = map[float64]int{
@@ -252,9 +277,10 @@ func NewBat(coreTx RcTx) {
 		geom.Radians(90):  3,
 		geom.Radians(0):   0,
 	}
-	batImg := loader.GetSpriteFromFile("bat_sheet.png")
+	batSheet := loadSprite("bat")
+	batImg := batSheet.Img
 	batWidth, batHeight := batImg.Bounds().Dx(), batImg.Bounds().Dy()
-	batCols, batRows := 3, 4
+	batCols, batRows := batSheet.Columns, batSheet.Rows
 	batScale := 0.25
 	// in pixels, radius and height to use for collision testing
 	batPxRadius, batPxHeight := 14.0, 25.0
@@ -274,13 +300,17 @@ func NewBat(coreTx RcTx) {
 	// 	redBoltProjectile.AddDebugLines(2, color.RGBA{0, 255, 0, 255})
 	// }
 
-	cnt += 1
-	y := float64(2+cnt/100)*batCollisionRadius*4 + batCollisionRadius*40
-	x := float64(2+cnt%100) * batCollisionRadius * 4
+	if po == nil {
+		cnt += 1
+		y := float64(2+cnt/100)*batCollisionRadius*4 + batCollisionRadius*40
+		x := float64(2+cnt%100) * batCollisionRadius * 4
+		po = &Position{X: x, Y: y, Z: 3}
+	}
 
-	NewEnemy(coreTx,
-		Position{X: x, Y: y, Z: 3},
+	return NewEnemy(coreTx,
+		*po,
 		DrawInfo{
+			Asset:             "bat",
 			Img:               batImg,
 			ImgLayer:          ImgLayerSprite,
 			Columns:           batCols,
@@ -293,6 +323,8 @@ func NewBat(coreTx RcTx) {
 		CollisionSpace{
 			CollisionRadius: batCollisionRadius,
 			CollisionHeight: batCollisionHeight,
+			CollisionLayer:  CollisionLayerEnemy,
+			CollisionMask:   enemyCollisionMask,
 		},
 		batVelocity,
 		3,
@@ -303,24 +335,29 @@ func NewBat(coreTx RcTx) {
 	// log.Printf("%v, %v", batCollisionRadius, batCollisionHeight)
 }
 
-func NewRock(coreTx RcTx) {
+func NewRock(coreTx RcTx, po *Position) RcTx {
 	// rock that can be jumped over but not walked through
-	rockImg := loader.GetSpriteFromFile("large_rock.png")
+	rockSheet := loadSprite("rock")
+	rockImg := rockSheet.Img
 	rockWidth, rockHeight := rockImg.Bounds().Dx(), rockImg.Bounds().Dy()
+	rockCols, rockRows := rockSheet.Columns, rockSheet.Rows
 	rockScale := 0.4
 	rockPxRadius, rockPxHeight := 24.0, 35.0
-	rockCollisionRadius := (rockScale * rockPxRadius) / float64(rockWidth)
-	rockCollisionHeight := (rockScale * rockPxHeight) / float64(rockHeight)
+	rockCollisionRadius := (rockScale * rockPxRadius) / (float64(rockWidth) / float64(rockCols))
+	rockCollisionHeight := (rockScale * rockPxHeight) / (float64(rockHeight) / float64(rockRows))
 
-	rockCols, rockRows := 1, 1
 	rockVelocity := 0.0
 
-	y := float64(2+cnt/100)*rockCollisionRadius*4 + rockCollisionRadius*60
-	x := float64(2+cnt%100) * rockCollisionRadius * 4
+	if po == nil {
+		y := float64(2+cnt/100)*rockCollisionRadius*4 + rockCollisionRadius*60
+		x := float64(2+cnt%100) * rockCollisionRadius * 4
+		po = &Position{X: x, Y: y, Z: 0}
+	}
 
-	NewEnemy(coreTx,
-		Position{X: x, Y: y, Z: 0},
+	return NewEnemy(coreTx,
+		*po,
 		DrawInfo{
+			Asset:    "rock",
 			Img:      rockImg,
 			ImgLayer: ImgLayerSprite,
 			Columns:  rockCols,
@@ -330,6 +367,8 @@ func NewRock(coreTx RcTx) {
 		CollisionSpace{
 			CollisionRadius: rockCollisionRadius,
 			CollisionHeight: rockCollisionHeight,
+			CollisionLayer:  CollisionLayerEnemy,
+			CollisionMask:   enemyCollisionMask,
 		},
 		rockVelocity,
 		0,
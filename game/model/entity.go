@@ -2,6 +2,7 @@ package model
 
 import (
 	"image/color"
+	"math"
 
 	"github.com/harbdog/raycaster-go"
 	"github.com/harbdog/raycaster-go/geom"
@@ -12,6 +13,25 @@ type Position struct {
 	Y float64
 	Z float64
 }
+
+// CollisionLayer is a set of collision layers, one bit each.
+// An entity is on the layers of its CollisionLayer, and when it moves it
+// collides with the walls and entities on the layers of its CollisionMask.
+// An entity without a CollisionMask collides with everything and one without a
+// CollisionLayer is on every layer, as entities were before there were layers.
+type CollisionLayer uint32
+
+const (
+	CollisionLayerWall CollisionLayer = 1 << iota
+	CollisionLayerPlayer
+	CollisionLayerEnemy
+	CollisionLayerProjectile
+	CollisionLayerPickup
+)
+
+// CollisionLayerAll is the mask of every layer.
+const CollisionLayerAll CollisionLayer = math.MaxUint32
+
 type Entity struct {
 	RgId            ID
 	RgType          RegoterEnum
@@ -26,6 +46,8 @@ type Entity struct {
 	LastMoveRotate  float64
 	CollisionRadius float64
 	CollisionHeight float64
+	CollisionLayer  CollisionLayer
+	CollisionMask   CollisionLayer
 	MapColor        color.RGBA
 	ParentId        ID
 }
@@ -37,3 +59,27 @@ func (e *Entity) Pos() *geom.Vector2 {
 func (e *Entity) PosZ() float64 {
 	return e.Position.Z
 }
+
+// collisionMask returns the layers e collides with when it moves.
+func (e *Entity) collisionMask() CollisionLayer {
+	if e.CollisionMask == 0 {
+		return CollisionLayerAll
+	}
+	return e.CollisionMask
+}
+
+// collisionLayer returns the layers e is on.
+func (e *Entity) collisionLayer() CollisionLayer {
+	if e.CollisionLayer == 0 {
+		return CollisionLayerAll
+	}
+	return e.CollisionLayer
+}
+
+// collidesWith reports whether e collides with other when e moves into it.
+// The rule is one-way, only the mask of the mover and the layers of other count:
+// a ghost leaving enemies out of its mask passes through them, while an enemy
+// moving into the ghost still hits it.
+func (e *Entity) collidesWith(other *Entity) bool {
+	return e.collisionMask()&other.collisionLayer() != 0
+}
@@ -1,19 +1,32 @@
 package model
 
 import (
+	"context"
 	"fmt"
+	"lintech/rego/game/loader"
 	"log"
 	"math"
-	"math/rand"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/spf13/viper"
 )
 
+const (
+	// Core draws a frame in far less, it is stuck when it takes longer
+	drawTimeout = 5 * time.Second
+	// the regoters answer within a few ticks
+	snapshotTimeout = 5 * time.Second
+	// the regoters stop within a few ticks
+	shutdownTimeout = 5 * time.Second
+	// reactors blocked on each other for longer are reported as a deadlock
+	watchdogInterval = 2 * time.Second
+)
+
 // type gameTxMsgbox chan<- IRegoterEvent
 // type gameRxMsgbox <-chan ICoreEvent
 
@@ -21,12 +34,16 @@ type Game struct {
 	Reactor
 	// Raycaster
 	menu   *DemoMenu
+	hud    *Hud
 	paused bool
+	// the game ends after this tick
+	exiting bool
 
 	cfg           GameCfg
 	coreTx        RcTx
 	audioPlayer   *RegoAudioPlayer
 	createSprites func(RcTx)
+	stopWatchdog  func()
 }
 
 func createSpritesFunc(max_sprites int) func(coreTx RcTx) {
@@ -34,12 +51,12 @@ func createSpritesFunc(max_sprites int) func(coreTx RcTx) {
 	max_gen_sprites := max_sprites
 	return func(coreTx RcTx) {
 		if gened_sprites < max_gen_sprites {
-			r := rand.Intn(10)
+			r := WorldOf(coreTx).randIntn(10)
 			if r == 1 {
-				NewSorcerer(coreTx)
-				NewWalker(coreTx)
-				NewBat(coreTx)
-				NewRock(coreTx)
+				NewSorcerer(coreTx, nil)
+				NewWalker(coreTx, nil)
+				NewBat(coreTx, nil)
+				NewRock(coreTx, nil)
 				gened_sprites++
 			}
 		}
@@ -49,9 +66,22 @@ func createSpritesFunc(max_sprites int) func(coreTx RcTx) {
 // Update - Allows the game to run logic such as updating the world, gathering input, and playing audio.
 // Update is called every tick (1/60 [s] by default).
 func (g *Game) Update() error {
+	if g.exiting {
+		return ebiten.Termination
+	}
+	select {
+	case <-g.world.Done():
+		// Core failed, there is no world left to run
+		if err := g.world.Err(); err != nil {
+			return err
+		}
+		return ebiten.Termination
+	default:
+	}
 	if !g.paused {
 		m := ReactorEventMessage{g.tx, EventGameTick{}}
-		g.coreTx <- m
+		send(g.coreTx, m)
+		g.world.Settle()
 	}
 	// If we add Same Sprites in CreateGame(), they will show same frame of Animation at each Tick.
 	// Becasue they have same count of Update Ticks.
@@ -73,19 +103,56 @@ func (g *Game) Run() {
 	// Debug
 	//ebiten.SetTPS(1)
 	if err := ebiten.RunGame(g); err != nil {
-		log.Fatal(err)
+		log.Printf("Error: %v", err)
 	}
+	g.Shutdown()
+}
+
+// Shutdown stops Core and all regoters of the game, so another game can be created.
+func (g *Game) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	select {
+	case <-g.world.Done():
+		// Core stopped on a failure and released the regoters itself
+	default:
+		if err := Shutdown(ctx, g.coreTx); err != nil {
+			log.Printf("Warning: Shutdown failed: %v", err)
+		}
+	}
+	if err := g.hud.Stop(ctx); err != nil {
+		log.Printf("Warning: Stopping the HUD failed: %v", err)
+	}
+	if g.stopWatchdog != nil {
+		g.stopWatchdog()
+	}
+	g.world.Close()
+}
+
+// exit ends the game after the current tick, Run then returns.
+func (g *Game) exit() {
+	g.exiting = true
 }
 
 // Draw draws the game screen.
 // Draw is called every frame (typically 1/60[s] for 60Hz display).
 func (g *Game) Draw(screen *ebiten.Image) {
-	m := ReactorEventMessage{g.tx, EventDraw{Screen: screen}}
-	g.coreTx <- m
+	m, drawn := Request[EventDrawDone](EventDraw{Screen: screen})
+	if s := g.world.Scheduler(); s != nil {
+		// frames come at any time, so they must not advance the simulation
+		s.ProcessNow(m)
+	} else {
+		send(g.coreTx, m)
+	}
 	//While Core is drawing, we play background music
 	g.playBackGroundAudio()
-	<-g.rx
+	ctx, cancel := context.WithTimeout(context.Background(), drawTimeout)
+	defer cancel()
+	if _, err := drawn.Await(ctx); err != nil {
+		log.Printf("Warning: Drawing the frame failed: %v", err)
+	}
 
+	g.hud.draw(screen)
 	// draw menu (if active)
 	g.menu.draw(screen)
 
@@ -103,11 +170,12 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 func NewGame(coreTx RcTx, cfg GameCfg, createSprites func(RcTx)) *Game {
 	//loadCrosshairsResource()
 	t := &Game{
-		Reactor:       NewReactor(),
+		Reactor:       WorldOf(coreTx).NewReactor(),
 		cfg:           cfg,
 		coreTx:        coreTx,
 		audioPlayer:   LoadAudioPlayer("dark-castle-night.mp3"),
 		createSprites: createSprites,
+		hud:           NewHud(coreTx),
 	}
 	t.menu = t.createMenu()
 	return t
@@ -128,12 +196,37 @@ func CreateGame(max_sprites int) *Game {
 
 	// initialize Game object
 	cfg := initConfig()
-	coreTx := NewCore(cfg)
+	input, err := newInputSource(&cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := loader.SetAssetLayers(cfg.ModDir, cfg.AssetPack); err != nil {
+		log.Fatal(err)
+	}
+	mapObj, err := loadMap(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	manifest, err := loadManifest(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	coreTx := NewCore(cfg, mapObj, manifest)
+	if cfg.HotReload {
+		go watchContent(coreTx, cfg, mapObj)
+	}
 	g := NewGame(coreTx, cfg, createSprites)
+	if !cfg.Deterministic {
+		// the deterministic scheduler runs all reactors on the goroutine of the game
+		g.stopWatchdog = StartWatchdog(watchdogInterval, func(d Deadlock) {
+			log.Printf("Error: %v", d)
+		})
+	}
 
 	// create crosshairs and weapon
 	NewCrosshairs(coreTx)
-	NewPlayer(coreTx)
+	NewPlayer(coreTx, mapObj.PlayerStart(), input)
+	spawnMapActors(coreTx, mapObj)
 
 	// Todo
 	// init the sprites
@@ -146,6 +239,42 @@ func CreateGame(max_sprites int) *Game {
 	return g
 }
 
+// mapSpawners create the actor named by a spawn point of the level.
+// Bats hang from their position, so their spawn points need a z above the floor.
+var mapSpawners = map[string]func(RcTx, *Position) RcTx{
+	"sorcerer": NewSorcerer,
+	"walker":   NewWalker,
+	"bat":      NewBat,
+	"rock":     NewRock,
+}
+
+func spawnMapActors(coreTx RcTx, mapObj *loader.Map) {
+	for _, s := range mapObj.Spawns() {
+		spawn, ok := mapSpawners[s.Name]
+		if !ok {
+			log.Printf("Warning: Unknown %v %q in level %q.", s.Kind, s.Name, mapObj.Name())
+			continue
+		}
+		spawn(coreTx, &Position{X: s.X, Y: s.Y, Z: s.Z})
+	}
+}
+
+// loadMap loads the level file set in config, or the embedded demo level.
+func loadMap(cfg GameCfg) (*loader.Map, error) {
+	if cfg.Level == "" {
+		return loader.LoadAssetMap(loader.DefaultLevel)
+	}
+	return loader.LoadMapFile(cfg.Level)
+}
+
+// loadManifest loads the asset manifest of the directory set in config, or the embedded one.
+func loadManifest(cfg GameCfg) (*loader.Manifest, error) {
+	if cfg.AssetDir == "" {
+		return loader.DefaultManifest()
+	}
+	return loader.LoadManifest(os.DirFS(cfg.AssetDir), loader.ManifestFile)
+}
+
 func (g *Game) playBackGroundAudio() {
 	g.audioPlayer.PlayWithVolume(0.5, false)
 }
@@ -172,6 +301,10 @@ func initConfig() GameCfg {
 	if userHomePath != "" {
 		userHomePath = userHomePath + "/.raycaster-go-demo"
 		viper.AddConfigPath(userHomePath)
+		viper.SetDefault("modDir", userHomePath+"/mods")
+		viper.SetDefault("snapshot", userHomePath+"/quicksave.json")
+	} else {
+		viper.SetDefault("snapshot", "quicksave.json")
 	}
 	viper.AddConfigPath(".")
 
@@ -184,6 +317,17 @@ func initConfig() GameCfg {
 	viper.SetDefault("screen.renderAudioDistance", 50)
 	viper.SetDefault("screen.renderFloor", true)
 	viper.SetDefault("screen.fovDegrees", 68)
+	viper.SetDefault("level", "")
+	viper.SetDefault("assets", "")
+	viper.SetDefault("assetPack", "")
+	viper.SetDefault("hotReload", false)
+	viper.SetDefault("deterministic", false)
+	viper.SetDefault("seed", 1)
+	viper.SetDefault("mailbox.regoter", defaultRegoterMailboxCapacity)
+	viper.SetDefault("mailbox.core", defaultCoreMailboxCapacity)
+	viper.SetDefault("workers", 0)
+	viper.SetDefault("record", "")
+	viper.SetDefault("replay", "")
 
 	if cfg.OsType == OsTypeBrowser {
 		viper.SetDefault("screen.width", 800)
@@ -212,6 +356,19 @@ func initConfig() GameCfg {
 	cfg.RenderDistance = viper.GetFloat64("screen.renderDistance")
 	cfg.RenderAudioDistance = viper.GetFloat64("screen.renderAudioDistance")
 	cfg.RenderFloorTex = viper.GetBool("screen.renderFloor")
+	cfg.Level = viper.GetString("level")
+	cfg.AssetDir = viper.GetString("assets")
+	cfg.ModDir = viper.GetString("modDir")
+	cfg.AssetPack = viper.GetString("assetPack")
+	cfg.HotReload = viper.GetBool("hotReload")
+	cfg.Deterministic = viper.GetBool("deterministic")
+	cfg.Seed = viper.GetInt64("seed")
+	cfg.MailboxCapacity = configCapacity("mailbox.regoter", defaultRegoterMailboxCapacity)
+	cfg.CoreMailboxCapacity = configCapacity("mailbox.core", defaultCoreMailboxCapacity)
+	cfg.Workers = viper.GetInt("workers")
+	cfg.Record = viper.GetString("record")
+	cfg.Replay = viper.GetString("replay")
+	cfg.Snapshot = viper.GetString("snapshot")
 	cfg.ShowSpriteBoxes = viper.GetBool("showSpriteBoxes")
 	// cfg.ShowSpriteBoxes = true
 	cfg.Debug = viper.GetBool("debug")
@@ -225,6 +382,17 @@ func initConfig() GameCfg {
 	return cfg
 }
 
+// configCapacity returns the mailbox capacity set for key, or def if it is below 1.
+// A mailbox without room makes every send wait, a negative one can not be made.
+func configCapacity(key string, def int) int {
+	capacity := viper.GetInt(key)
+	if capacity < 1 {
+		log.Printf("Warning: %v must be at least 1, not %v, using %v.", key, capacity, def)
+		return def
+	}
+	return capacity
+}
+
 func (g *Game) SaveConfig() error {
 	userConfigPath, _ := os.UserHomeDir()
 	if userConfigPath == "" {
@@ -250,6 +418,36 @@ func (g *Game) SaveConfig() error {
 	return err
 }
 
+// quickSave writes a snapshot of the world to the snapshot file set in config.
+// Core answers after the next ticks, so it waits on its own goroutine.
+func (g *Game) quickSave() {
+	path := g.cfg.Snapshot
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+		defer cancel()
+		snap, err := TakeSnapshot(ctx, g.coreTx)
+		if err == nil {
+			err = SaveSnapshotFile(path, snap)
+		}
+		if err != nil {
+			log.Printf("Warning: Quicksave failed: %v", err)
+			return
+		}
+		log.Printf("Saved snapshot %q", path)
+	}()
+}
+
+// quickLoad restores the snapshot file set in config.
+func (g *Game) quickLoad() {
+	snap, err := LoadSnapshotFile(g.cfg.Snapshot)
+	if err != nil {
+		log.Printf("Warning: Quickload failed: %v", err)
+		return
+	}
+	RestoreSnapshot(g.coreTx, snap)
+	log.Printf("Restored snapshot %q", g.cfg.Snapshot)
+}
+
 var mouse = MousePosition{math.MinInt32, math.MinInt32}
 
 func (g *Game) handleInput() bool {
@@ -261,7 +459,7 @@ func (g *Game) handleInput() bool {
 			} else {
 				g.closeMenu()
 				m := ReactorEventMessage{g.tx, EventCfgChanged{Cfg: g.cfg}}
-				g.coreTx <- m
+				send(g.coreTx, m)
 			}
 		} else {
 			g.openMenu()
@@ -276,6 +474,15 @@ func (g *Game) handleInput() bool {
 		g.openMenu()
 	}
 
+	if !g.menu.active {
+		if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+			g.quickSave()
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+			g.quickLoad()
+		}
+	}
+
 	if g.paused {
 		// currently only paused when menu is active, one could consider other pauses not the subject of this demo
 		return g.menu.active
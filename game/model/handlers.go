@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A regoter declares the events it handles with On, before Start, and gets
+// the ProcessMessage of Reactor. Regoter types outside this package are built
+// the same way from World.NewReactor, On, Register and Send.
+
+// On makes r handle the events of type T with handler, in place of an earlier one.
+func On[T IReactorEvent](r *Reactor, handler func(sender RcTx, e T)) {
+	OnFallible(r, func(sender RcTx, e T) error {
+		handler(sender, e)
+		return nil
+	})
+}
+
+// OnFallible is On for handlers that fail, their errors are reported to the
+// supervisor of r.
+func OnFallible[T IReactorEvent](r *Reactor, handler func(sender RcTx, e T) error) {
+	if r.handlers == nil {
+		r.handlers = map[reflect.Type]func(RcTx, IReactorEvent) error{}
+	}
+	var e T
+	r.handlers[reflect.TypeOf(e)] = func(sender RcTx, e IReactorEvent) error {
+		return handler(sender, e.(T))
+	}
+}
+
+// OnUnknown makes r hand the events without a handler to fallback.
+// Without a fallback they are errors, reported to the supervisor of r.
+func (r *Reactor) OnUnknown(fallback func(sender RcTx, e IReactorEvent) error) {
+	r.fallback = fallback
+}
+
+// Unregistering drops the events after r asked Core to unregister it,
+// until Core confirms it.
+func (r *Reactor) Unregistering() {
+	r.unregistered = true
+}
+
+// ProcessMessage calls the handler of the event. EventUnregisterConfirmed
+// stops the reactor, unless it has a handler for it.
+func (r *Reactor) ProcessMessage(m ReactorEventMessage) error {
+	h, ok := r.handlers[reflect.TypeOf(m.event)]
+	_, confirmed := m.event.(EventUnregisterConfirmed)
+	switch {
+	case confirmed && !ok:
+		r.running = false
+	case r.unregistered && !confirmed:
+	case ok:
+		return h(m.sender, m.event)
+	case r.fallback != nil:
+		return r.fallback(m.sender, m.event)
+	default:
+		return fmt.Errorf("unknown event %T", m.event)
+	}
+	return nil
+}
+
+// NewMessage returns the message of event from sender.
+func NewMessage(sender RcTx, event IReactorEvent) ReactorEventMessage {
+	return ReactorEventMessage{sender, event}
+}
+
+// Tx returns the channel to send messages to r.
+func (r *Reactor) Tx() RcTx {
+	return r.tx
+}
+
+// Send sends event from r to tx.
+func (r *Reactor) Send(tx RcTx, event IReactorEvent) {
+	send(tx, ReactorEventMessage{r.tx, event})
+}
+
+// Register registers r with Core as the regoter of data, once it is started.
+func (r *Reactor) Register(coreTx RcTx, data RegoterData) {
+	send(coreTx, ReactorEventMessage{r.tx, EventRegisterRegoter{r.tx, data, r.done}})
+}
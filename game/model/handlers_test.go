@@ -0,0 +1,80 @@
+package model_test
+
+import (
+	"context"
+	"fmt"
+	"lintech/rego/game/loader"
+	"lintech/rego/game/model"
+	"testing"
+	"time"
+)
+
+// beacon is a regoter type from outside the model package.
+// It answers EventAskHealth with the ticks it got.
+type beacon struct {
+	model.Reactor
+	ticks   int
+	unknown []string
+}
+
+func newBeacon(coreTx model.RcTx, position model.Position) *beacon {
+	b := &beacon{Reactor: model.WorldOf(coreTx).NewReactor()}
+	model.On(&b.Reactor, b.onTick)
+	model.On(&b.Reactor, b.onAskHealth)
+	b.OnUnknown(b.onUnknown)
+	b.Reactor.Start(b)
+	b.Register(coreTx, model.RegoterData{Entity: model.Entity{
+		RgId: <-model.IdGen, RgType: model.RegoterEnumSprite, Position: position}})
+	return b
+}
+
+func (b *beacon) onTick(sender model.RcTx, e model.EventUpdateTick) {
+	b.ticks++
+}
+
+func (b *beacon) onAskHealth(sender model.RcTx, e model.EventAskHealth) {
+	b.Send(sender, model.EventHealth{Health: b.ticks})
+}
+
+func (b *beacon) onUnknown(sender model.RcTx, e model.IReactorEvent) error {
+	b.unknown = append(b.unknown, fmt.Sprintf("%T", e))
+	return nil
+}
+
+func TestRegoterFromOutside(t *testing.T) {
+	mapObj, err := loader.LoadAssetMap(loader.DefaultLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreTx := model.NewHeadlessCore(model.GameCfg{}, mapObj)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	defer func() {
+		if err := model.Shutdown(ctx, coreTx); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	}()
+	start := mapObj.PlayerStart()
+	b := newBeacon(coreTx, model.Position{X: start.X, Y: start.Y})
+
+	coreTx <- model.NewMessage(nil, model.EventGameTick{})
+	// Core sent the tick once it answers
+	if _, err := model.Ask[model.EventTickStats](ctx, coreTx, model.EventAskTickStats{}); err != nil {
+		t.Fatal(err)
+	}
+	b.Tx() <- model.NewMessage(nil, model.EventGameTick{})
+	health, err := model.Ask[model.EventHealth](ctx, b.Tx(), model.EventAskHealth{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Health != 1 {
+		t.Errorf("got %v ticks, want 1", health.Health)
+	}
+	found := false
+	for _, u := range b.unknown {
+		found = found || u == "model.EventGameTick"
+	}
+	if !found {
+		t.Errorf("fallback got %v, want model.EventGameTick among them", b.unknown)
+	}
+}
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"context"
+	"lintech/rego/game/loader"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/harbdog/raycaster-go"
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// expectEvent waits for the next event of type T on rx, skipping other events.
+func expectEvent[T any](t *testing.T, rx <-chan ReactorEventMessage) T {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case m := <-rx:
+			if e, ok := m.event.(T); ok {
+				return e
+			}
+		case <-timeout:
+			var e T
+			t.Fatalf("no %T received", e)
+			return e
+		}
+	}
+}
+
+func newHeadlessTestCore(t *testing.T) (RcTx, loader.PlayerStart) {
+	t.Helper()
+	return newHeadlessTestCoreWith(t, GameCfg{})
+}
+
+// newHeadlessTestCoreWith returns a headless Core in the demo level, running as cfg sets.
+func newHeadlessTestCoreWith(t *testing.T, cfg GameCfg) (RcTx, loader.PlayerStart) {
+	t.Helper()
+	mapObj, err := loader.LoadAssetMap(loader.DefaultLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreTx := NewHeadlessCore(cfg, mapObj)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := Shutdown(ctx, coreTx); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	})
+	return coreTx, mapObj.PlayerStart()
+}
+
+// registerTestRegoter registers an entity without images, its events arrive on the returned channel.
+func registerTestRegoter(t *testing.T, coreTx RcTx, entity Entity) chan ReactorEventMessage {
+	t.Helper()
+	rx := make(chan ReactorEventMessage, 100)
+	coreTx <- ReactorEventMessage{rx, EventRegisterRegoter{rx, RegoterData{Entity: entity}, nil}}
+	expectEvent[EventCfgChanged](t, rx)
+	return rx
+}
+
+func TestHeadlessCoreMoves(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	entity := Entity{
+		RgId:     <-IdGen,
+		RgType:   RegoterEnumSprite,
+		Position: Position{X: start.X, Y: start.Y},
+		Anchor:   raycaster.AnchorBottom,
+		Velocity: 0.05,
+	}
+	rx := registerTestRegoter(t, coreTx, entity)
+
+	coreTx <- ReactorEventMessage{rx, EventGameTick{}}
+	tick := expectEvent[EventUpdateTick](t, rx)
+	if tick.RgEntity.Position != entity.Position {
+		t.Fatalf("got position %+v before moving, want %+v", tick.RgEntity.Position, entity.Position)
+	}
+
+	coreTx <- ReactorEventMessage{rx, EventMovement{RgId: entity.RgId}}
+	coreTx <- ReactorEventMessage{rx, EventGameTick{}}
+	tick = expectEvent[EventUpdateTick](t, rx)
+	if got := tick.RgEntity.Position.X - start.X; math.Abs(got-0.05) > 1e-9 {
+		t.Errorf("moved %v, want 0.05", got)
+	}
+}
+
+func TestHeadlessCoreCollidesAndDamages(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	mover := Entity{
+		RgId:            <-IdGen,
+		RgType:          RegoterEnumSprite,
+		Position:        Position{X: start.X, Y: start.Y},
+		Anchor:          raycaster.AnchorBottom,
+		Velocity:        0.1,
+		CollisionRadius: 0.1,
+		CollisionHeight: 0.5,
+		CollisionLayer:  CollisionLayerEnemy,
+		CollisionMask:   CollisionLayerEnemy,
+	}
+	peer := mover
+	peer.RgId = <-IdGen
+	peer.Position.X += 0.25
+	peer.Velocity = 0
+	moverRx := registerTestRegoter(t, coreTx, mover)
+	peerRx := registerTestRegoter(t, coreTx, peer)
+
+	coreTx <- ReactorEventMessage{moverRx, EventMovement{RgId: mover.RgId}}
+	collision := expectEvent[EventCollision](t, moverRx)
+	contact := collision.nearest()
+	if contact.peer != peer.RgId || math.Abs(contact.distance-0.05) > 1e-9 || contact.normal.X != -1 {
+		t.Errorf("got contact %+v, want peer %v at distance 0.05 with normal -X", contact, peer.RgId)
+	}
+	if got := expectEvent[EventCollision](t, peerRx).nearest().peer; got != mover.RgId {
+		t.Errorf("peer got collision with %v, want %v", got, mover.RgId)
+	}
+
+	coreTx <- ReactorEventMessage{moverRx, EventDamagePeer{peer: peer.RgId, damage: 7}}
+	if got := expectEvent[EventHealthChange](t, peerRx).change; got != 7 {
+		t.Errorf("got damage %v, want 7", got)
+	}
+}
+
+// wanderer turns by a random angle each tick and walks on.
+type wanderer struct {
+	Reactor
+	entity Entity
+}
+
+func (w *wanderer) ProcessMessage(m ReactorEventMessage) error {
+	switch e := m.event.(type) {
+	case EventUpdateTick:
+		w.entity = e.RgEntity
+		move := Movement{Velocity: w.entity.Velocity, VissionRotate: w.world.randFloat64() * geom.Pi2}
+		m.sender <- ReactorEventMessage{w.tx, EventMovement{RgId: w.entity.RgId, Move: move}}
+	case EventUnregisterConfirmed:
+		w.running = false
+	}
+	return nil
+}
+
+// runWanderers returns the positions of wanderers after ticks ticks of a deterministic run.
+func runWanderers(t *testing.T, seed int64, ticks int) []Position {
+	t.Helper()
+	coreTx, start := newHeadlessTestCoreWith(t, GameCfg{Deterministic: true, Seed: seed})
+	world := WorldOf(coreTx)
+
+	var ws []*wanderer
+	for i := 0; i < 4; i++ {
+		w := &wanderer{Reactor: world.NewReactor()}
+		w.entity = Entity{
+			RgId:            <-IdGen,
+			RgType:          RegoterEnumSprite,
+			Position:        Position{X: start.X + float64(i)*0.3, Y: start.Y},
+			Anchor:          raycaster.AnchorBottom,
+			Velocity:        0.05,
+			CollisionRadius: 0.1,
+			CollisionHeight: 0.5,
+			CollisionLayer:  CollisionLayerEnemy,
+			CollisionMask:   enemyCollisionMask,
+		}
+		w.Reactor.Start(w)
+		coreTx <- ReactorEventMessage{w.tx, EventRegisterRegoter{w.tx, RegoterData{Entity: w.entity}, w.done}}
+		ws = append(ws, w)
+	}
+	world.Settle()
+	for i := 0; i < ticks; i++ {
+		coreTx <- ReactorEventMessage{nil, EventGameTick{}}
+		world.Settle()
+	}
+
+	positions := make([]Position, len(ws))
+	for i, w := range ws {
+		positions[i] = w.entity.Position
+	}
+	return positions
+}
+
+func TestDeterministicRunsRepeat(t *testing.T) {
+	// the worlds of the runs and of a game on goroutines of its own live side by side
+	free, _ := newHeadlessTestCore(t)
+	first := runWanderers(t, 42, 200)
+	second := runWanderers(t, 42, 200)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("wanderer %v ended at %+v and %+v", i, first[i], second[i])
+		}
+	}
+	if other := runWanderers(t, 43, 200); other[0] == first[0] {
+		t.Errorf("seeds 42 and 43 both ended at %+v", first[0])
+	}
+	if WorldOf(free).Scheduler() != nil {
+		t.Error("the Core on goroutines of its own runs deterministically")
+	}
+}
+
+// hurt adds up the damage it takes.
+type hurt struct {
+	Reactor
+	damage int
+}
+
+func (h *hurt) ProcessMessage(m ReactorEventMessage) error {
+	if e, ok := m.event.(EventHealthChange); ok {
+		h.damage += e.change
+	}
+	return nil
+}
+
+func TestDeterministicSendsDoNotWaitForRoom(t *testing.T) {
+	const capacity, hits = 2, 10
+	coreTx, start := newHeadlessTestCoreWith(t, GameCfg{Deterministic: true, Seed: 1})
+	world := WorldOf(coreTx)
+	h := &hurt{Reactor: NewReactorWithCapacity(capacity)}
+	h.world = world
+	h.Reactor.Start(h)
+	id := <-IdGen
+	entity := Entity{RgId: id, RgType: RegoterEnumSprite, Position: Position{X: start.X, Y: start.Y}}
+	coreTx <- ReactorEventMessage{h.tx, EventRegisterRegoter{h.tx, RegoterData{Entity: entity}, h.done}}
+	world.Settle()
+
+	// Core handles all of them before the regoter gets its turn
+	for i := 0; i < hits; i++ {
+		coreTx <- ReactorEventMessage{nil, EventDamagePeer{peer: id, damage: 1}}
+	}
+	world.Settle()
+	if h.damage != hits {
+		t.Errorf("got damage %v, want %v", h.damage, hits)
+	}
+}
@@ -0,0 +1,181 @@
+package model
+
+import (
+	"io/fs"
+	"lintech/rego/game/loader"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// wait for changes to settle before reloading, editors often write a file in several steps
+const hotReloadDelay = 200 * time.Millisecond
+
+// watchContent watches the level file set in config, the tilesets it imports,
+// the asset directory and the mod directory, and sends EventReloadContent to
+// Core when they change.
+// The embedded level and assets can not change, so they are not watched.
+func watchContent(coreTx RcTx, cfg GameCfg, mapObj *loader.Map) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Warning: Hot reload is not available: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	content := newContentWatch(cfg, mapObj)
+	watched := content.watchFiles(watcher)
+	for _, dir := range content.dirs {
+		if err := watchDirs(watcher, dir); err != nil {
+			log.Printf("Warning: Can not watch assets %q: %v", dir, err)
+		} else {
+			watched++
+		}
+	}
+	if watched == 0 {
+		log.Print("Warning: Hot reload needs a level file or an asset directory on disk.")
+		return
+	}
+	log.Print("Hot reload enabled")
+
+	reload := time.NewTimer(hotReloadDelay)
+	reload.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !content.isContentChange(event) {
+				continue
+			}
+			if event.Has(fsnotify.Create) && content.inDirs(event.Name) {
+				// files in new asset directories change assets too
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					if err := watchDirs(watcher, event.Name); err != nil {
+						log.Printf("Warning: Can not watch assets %q: %v", event.Name, err)
+					}
+				}
+			}
+			reload.Reset(hotReloadDelay)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Warning: Hot reload: %v", err)
+
+		case <-reload.C:
+			if mapObj := reloadContent(coreTx, cfg); mapObj != nil {
+				// the level may import other tilesets now
+				content.setMap(cfg, mapObj)
+				content.watchFiles(watcher)
+			}
+		}
+	}
+}
+
+// contentWatch is the content of the game on disk.
+type contentWatch struct {
+	// the level file and its tilesets
+	files []string
+	// the asset and mod directories, with the directories below them
+	dirs []string
+}
+
+func newContentWatch(cfg GameCfg, mapObj *loader.Map) *contentWatch {
+	c := &contentWatch{}
+	c.setMap(cfg, mapObj)
+	if cfg.AssetDir != "" {
+		c.dirs = append(c.dirs, cfg.AssetDir)
+	}
+	// the mod directory is optional, it is only watched if there is one
+	if fi, err := os.Stat(cfg.ModDir); cfg.ModDir != "" && err == nil && fi.IsDir() {
+		c.dirs = append(c.dirs, cfg.ModDir)
+	}
+	return c
+}
+
+// setMap makes the files of c the level file set in config and the tilesets of mapObj.
+func (c *contentWatch) setMap(cfg GameCfg, mapObj *loader.Map) {
+	c.files = nil
+	if cfg.Level == "" {
+		return
+	}
+	c.files = append(c.files, filepath.Clean(cfg.Level))
+	if mapObj != nil {
+		for _, ts := range mapObj.Tilesets() {
+			c.files = append(c.files, filepath.Clean(ts))
+		}
+	}
+}
+
+// watchFiles adds the directories of the files of c to watcher and returns how
+// many it watches.
+func (c *contentWatch) watchFiles(watcher *fsnotify.Watcher) int {
+	watched := 0
+	for _, f := range c.files {
+		// watch the directory, editors may replace the file instead of writing to it
+		if err := watcher.Add(filepath.Dir(f)); err != nil {
+			log.Printf("Warning: Can not watch level %q: %v", f, err)
+		} else {
+			watched++
+		}
+	}
+	return watched
+}
+
+// inDirs reports whether name is in one of the directories of c.
+func (c *contentWatch) inDirs(name string) bool {
+	for _, dir := range c.dirs {
+		rel, err := filepath.Rel(dir, name)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+// isContentChange reports whether event changes the level file, its tilesets or an asset.
+func (c *contentWatch) isContentChange(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	for _, f := range c.files {
+		if filepath.Clean(event.Name) == f {
+			return true
+		}
+	}
+	return c.inDirs(event.Name)
+}
+
+// watchDirs adds the directory root and the directories below it to watcher.
+func watchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return watcher.Add(path)
+	})
+}
+
+// reloadContent loads the level and the assets, hands them to Core and returns the level.
+// On errors the game keeps running with what it has and it returns nil.
+func reloadContent(coreTx RcTx, cfg GameCfg) *loader.Map {
+	mapObj, err := loadMap(cfg)
+	if err != nil {
+		log.Printf("Warning: Reload of level failed: %v", err)
+		return nil
+	}
+	manifest, err := loadManifest(cfg)
+	if err != nil {
+		log.Printf("Warning: Reload of assets failed: %v", err)
+		return nil
+	}
+	send(coreTx, ReactorEventMessage{nil, EventReloadContent{Map: mapObj, Manifest: manifest}})
+	return mapObj
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"lintech/rego/game/loader"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// smallLevel is a level of one free cell surrounded by walls.
+const smallLevel = `{"name": "small", "width": 3, "height": 3, "player": {"x": 1.5, "y": 1.5},
+	"levels": [[[1, 1, 1], [1, 0, 1], [1, 1, 1]]]}`
+
+func readSmallLevel(t *testing.T) *loader.Map {
+	t.Helper()
+	m, err := loader.ReadMap(strings.NewReader(smallLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestIsContentChange(t *testing.T) {
+	dir := t.TempDir()
+	cfg := GameCfg{
+		Level:    filepath.Join(dir, "levels", "level.tmx"),
+		AssetDir: filepath.Join(dir, "assets"),
+		ModDir:   filepath.Join(dir, "mods"),
+	}
+	if err := os.Mkdir(cfg.ModDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tileset := filepath.Join(dir, "tilesets", "walls.tsx")
+	content := newContentWatch(cfg, nil)
+	content.files = append(content.files, tileset)
+	for _, c := range []struct {
+		name string
+		op   fsnotify.Op
+		want bool
+	}{
+		{cfg.Level, fsnotify.Write, true},
+		{cfg.Level, fsnotify.Chmod, false},
+		{filepath.Join(dir, "levels", "other.json"), fsnotify.Write, false},
+		{tileset, fsnotify.Write, true},
+		{filepath.Join(dir, "tilesets", "other.tsx"), fsnotify.Write, false},
+		{filepath.Join(cfg.AssetDir, "manifest.json"), fsnotify.Rename, true},
+		// a new directory and the files in it
+		{filepath.Join(cfg.AssetDir, "new"), fsnotify.Create, true},
+		{filepath.Join(cfg.AssetDir, "new", "wall.png"), fsnotify.Create, true},
+		{filepath.Join(cfg.AssetDir, "wall.png"), fsnotify.Remove, false},
+		{filepath.Join(dir, "assets2", "wall.png"), fsnotify.Write, false},
+		{filepath.Join(cfg.ModDir, "sprites", "bat_sheet.png"), fsnotify.Create, true},
+	} {
+		if got := content.isContentChange(fsnotify.Event{Name: c.name, Op: c.op}); got != c.want {
+			t.Errorf("%v %v: got %v, want %v", c.op, c.name, got, c.want)
+		}
+	}
+}
+
+func TestLoadContentKeepsLevelOnError(t *testing.T) {
+	demo, err := loader.LoadAssetMap(loader.DefaultLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := newCore(GameCfg{})
+	core.setMap(demo)
+	// the walls of the level are not in the manifest
+	if err := core.loadContent(readSmallLevel(t), &loader.Manifest{}); err == nil {
+		t.Fatal("want error for walls missing from the manifest")
+	}
+	if core.mapObj != demo || core.mapWidth != demo.Width() {
+		t.Errorf("got level %q, want %q kept", core.mapObj.Name(), demo.Name())
+	}
+}
+
+func TestReloadPlacesRegoters(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	playerRx := registerTestRegoter(t, coreTx, Entity{RgId: <-IdGen, RgType: RegoterEnumPlayer,
+		Position: Position{X: start.X, Y: start.Y, Z: 0.5}})
+	spriteRx := registerTestRegoter(t, coreTx, Entity{RgId: <-IdGen, RgType: RegoterEnumSprite,
+		Position: Position{X: start.X, Y: start.Y}})
+
+	// the start of the demo level is outside the small one
+	coreTx <- ReactorEventMessage{nil, EventReloadContent{Map: readSmallLevel(t)}}
+	expectEvent[EventUnregisterConfirmed](t, spriteRx)
+	coreTx <- ReactorEventMessage{nil, EventGameTick{}}
+	tick := expectEvent[EventUpdateTick](t, playerRx)
+	if want := (Position{X: 1.5, Y: 1.5, Z: 0.5}); tick.RgEntity.Position != want {
+		t.Errorf("got player at %+v, want %+v", tick.RgEntity.Position, want)
+	}
+}
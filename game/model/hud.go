@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Hud shows the health of the player and the enemies killed. It learns them
+// from the Bus of Core, it is subscribed to TopicPlayerDamaged and TopicEnemyDied.
+type Hud struct {
+	Reactor
+	bus *Bus
+	// read by draw on the goroutine of the game
+	health int32
+	kills  int32
+}
+
+// NewHud starts a Hud for the world of Core.
+func NewHud(coreTx RcTx) *Hud {
+	h := &Hud{Reactor: WorldOf(coreTx).NewReactor(), health: playerHealth}
+	On(&h.Reactor, h.eventHandleBus)
+	On(&h.Reactor, h.eventHandlePlayerDamaged)
+	On(&h.Reactor, h.eventHandleEnemyDied)
+	h.Reactor.Start(h)
+	send(coreTx, ReactorEventMessage{h.tx, EventAskBus{}})
+	return h
+}
+
+func (h *Hud) eventHandleBus(sender RcTx, e EventBus) {
+	h.bus = e.Bus
+	h.bus.Subscribe(TopicPlayerDamaged, h.tx)
+	h.bus.Subscribe(TopicEnemyDied, h.tx)
+}
+
+func (h *Hud) eventHandlePlayerDamaged(sender RcTx, e EventPlayerDamaged) {
+	atomic.StoreInt32(&h.health, int32(e.Health))
+}
+
+func (h *Hud) eventHandleEnemyDied(sender RcTx, e EventEnemyDied) {
+	atomic.AddInt32(&h.kills, 1)
+}
+
+// OnStop ends the subscriptions, nobody would take the events.
+func (h *Hud) OnStop() {
+	if h.bus != nil {
+		h.bus.UnsubscribeAll(h.tx)
+	}
+}
+
+// Health returns the health of the player.
+func (h *Hud) Health() int {
+	return int(atomic.LoadInt32(&h.health))
+}
+
+// Kills returns how many enemies died.
+func (h *Hud) Kills() int {
+	return int(atomic.LoadInt32(&h.kills))
+}
+
+func (h *Hud) draw(screen *ebiten.Image) {
+	msg := fmt.Sprintf("Health: %v  Kills: %v", h.Health(), h.Kills())
+	ebitenutil.DebugPrintAt(screen, msg, 4, screen.Bounds().Dy()-20)
+}
@@ -8,6 +8,20 @@ import (
 	"github.com/harbdog/raycaster-go/geom"
 )
 
+// InputSource gives the Movement and Action of the player for each tick.
+type InputSource interface {
+	Input(cfg GameCfg) (Movement, Action)
+}
+
+// ebitenInput reads the keyboard and the mouse.
+type ebitenInput struct {
+	mouse MousePosition
+}
+
+func (i *ebitenInput) Input(cfg GameCfg) (Movement, Action) {
+	return handlePlayerInput(cfg, &i.mouse)
+}
+
 func handlePlayerInput(cfg GameCfg, lastPosition *MousePosition) (Movement, Action) {
 	movement := Movement{}
 	action := Action{}
@@ -135,7 +149,7 @@ func handlePlayerInput(cfg GameCfg, lastPosition *MousePosition) (Movement, Acti
 
 	_, wheelY := ebiten.Wheel()
 	if wheelY != 0 {
-		action.nextWeapon = true
+		action.NextWeapon = true
 	}
 	// if ebiten.IsKeyPressed(ebiten.KeyDigit1) {
 	// 	p.SelectWeapon(0)
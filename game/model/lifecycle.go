@@ -0,0 +1,152 @@
+package model
+
+import (
+	"context"
+	"fmt"
+)
+
+// A reactor runs from Start until a handler clears running or Stop is called.
+// Its channel is never closed, others may still hold it, Done tells when it
+// stopped instead.
+
+// IOnStart is implemented by reactors doing something before their first message.
+type IOnStart interface {
+	OnStart()
+}
+
+// IOnStop is implemented by reactors doing something after their last message.
+type IOnStop interface {
+	OnStop()
+}
+
+// eventStop stops the reactor, it is handled by the Reactor itself.
+type eventStop struct{}
+
+// Done returns a channel closed when the reactor stopped.
+func (r *Reactor) Done() <-chan struct{} {
+	return r.done
+}
+
+// Stop asks the reactor to stop after the messages sent to it before and waits
+// until it stopped or ctx is done.
+func (r *Reactor) Stop(ctx context.Context) error {
+	stop := ReactorEventMessage{nil, eventStop{}}
+	if r.mailbox != nil && r.mailbox.scheduled {
+		// the goroutine settling the scheduler can not wait for room
+		r.mailbox.park(stop)
+	} else {
+		select {
+		case r.tx <- stop:
+			r.mailbox.notify()
+		case <-r.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	r.world.Settle()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Reactor) started(t IProcessMessage) {
+	r.running = true
+	if r.mailbox != nil {
+		r.mailbox.open(t, r.world)
+	}
+	if h, ok := t.(IOnStart); ok {
+		h.OnStart()
+	}
+}
+
+func (r *Reactor) handle(t IProcessMessage, m ReactorEventMessage) {
+	r.handleMessage(t, m)
+	if r.mailbox == nil {
+		return
+	}
+	r.mailbox.flushParked()
+	for _, o := range r.mailbox.takeOverflow() {
+		if !r.running {
+			return
+		}
+		r.handleMessage(t, o)
+	}
+}
+
+func (r *Reactor) handleMessage(t IProcessMessage, m ReactorEventMessage) {
+	if _, ok := m.event.(eventOverflowed); ok {
+		return
+	}
+	if _, ok := m.event.(eventStop); ok {
+		r.running = false
+		return
+	}
+	if r.failed {
+		if _, ok := m.event.(EventUnregisterConfirmed); ok {
+			r.running = false
+		}
+		return
+	}
+	defer r.recoverPanic(t, m)
+	if err := t.ProcessMessage(m); err != nil {
+		r.report(Failure{Event: m.event, Err: err}, r.policy)
+	}
+}
+
+func (r *Reactor) stopped(t IProcessMessage) {
+	if h, ok := t.(IOnStop); ok {
+		h.OnStop()
+	}
+	if r.mailbox != nil {
+		r.mailbox.close()
+	}
+	close(r.done)
+}
+
+func (g *Core) eventHandleShutdown(sender RcTx, e EventShutdown) {
+	done := []<-chan struct{}{g.done}
+	for _, l := range g.rgs {
+		for _, rg := range l {
+			if rg.done != nil {
+				done = append(done, rg.done)
+			}
+			g.removeRegoter(l, rg)
+		}
+	}
+	g.running = false
+	send(sender, ReactorEventMessage{g.tx, EventWorldStopped{Done: done}})
+}
+
+// OnStop releases the regoters still registered when Core stopped on a
+// failure, they would wait for it forever.
+func (g *Core) OnStop() {
+	for _, l := range g.rgs {
+		for _, rg := range l {
+			g.removeRegoter(l, rg)
+		}
+	}
+}
+
+// Shutdown unregisters every regoter of the world of Core, stops Core and
+// waits until all of them stopped or ctx is done.
+func Shutdown(ctx context.Context, coreTx RcTx) error {
+	m, stopped := Request[EventWorldStopped](EventShutdown{})
+	send(coreTx, m)
+	WorldOf(coreTx).Settle()
+	e, err := stopped.Await(ctx)
+	if err != nil {
+		return err
+	}
+	for _, done := range e.Done {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return fmt.Errorf("shutdown: %w", ctx.Err())
+		}
+	}
+	return nil
+}
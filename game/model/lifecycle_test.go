@@ -0,0 +1,83 @@
+package model
+
+import (
+	"context"
+	"lintech/rego/game/loader"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/harbdog/raycaster-go"
+)
+
+// hooked records its lifecycle hooks.
+type hooked struct {
+	Reactor
+	events []string
+}
+
+func (h *hooked) OnStart() {
+	h.events = append(h.events, "start")
+}
+
+func (h *hooked) OnStop() {
+	h.events = append(h.events, "stop")
+}
+
+func (h *hooked) ProcessMessage(m ReactorEventMessage) error {
+	h.events = append(h.events, "message")
+	return nil
+}
+
+func TestStopRunsHooks(t *testing.T) {
+	h := &hooked{Reactor: NewReactor()}
+	h.Reactor.Start(h)
+	h.tx <- ReactorEventMessage{nil, EventGameTick{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-h.Done():
+	default:
+		t.Fatal("Done is open after Stop")
+	}
+	// Done is closed after OnStop, so reading events is safe
+	if got := len(h.events); got != 3 || h.events[0] != "start" || h.events[1] != "message" || h.events[2] != "stop" {
+		t.Errorf("got %v, want [start message stop]", h.events)
+	}
+	if err := h.Stop(ctx); err != nil {
+		t.Errorf("stopping again: %v", err)
+	}
+}
+
+func TestShutdownStopsAllGoroutines(t *testing.T) {
+	mapObj, err := loader.LoadAssetMap(loader.DefaultLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := runtime.NumGoroutine()
+	coreTx := NewHeadlessCore(GameCfg{}, mapObj)
+	start := mapObj.PlayerStart()
+	for i := 0; i < 3; i++ {
+		NewEnemy(coreTx, Position{X: start.X, Y: start.Y + float64(i)*0.3}, DrawInfo{}, 1,
+			CollisionSpace{}, 0.01, 0, raycaster.AnchorBottom, nil)
+	}
+	coreTx <- ReactorEventMessage{nil, EventGameTick{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Shutdown(ctx, coreTx); err != nil {
+		t.Fatal(err)
+	}
+	// the goroutines end right after closing Done
+	for runtime.NumGoroutine() > before {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("%v goroutines left, %v before", runtime.NumGoroutine(), before)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
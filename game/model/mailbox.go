@@ -0,0 +1,366 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Mailbox capacities unless GameCfg sets others.
+// Core gets more room, all regoters send to it.
+const (
+	defaultRegoterMailboxCapacity = 100
+	defaultCoreMailboxCapacity    = 1000
+)
+
+// OverflowPolicy tells what sending does when the mailbox of the receiver is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest messages of the mailbox until the message fits.
+	OverflowDropOldest
+	// OverflowDropNewest drops the message.
+	OverflowDropNewest
+	// OverflowCoalesce keeps the message aside, replacing an earlier one of its
+	// type that did not fit either. The reactor handles it after its next message.
+	OverflowCoalesce
+)
+
+// overflowPolicy returns the policy for sending e. Game ticks and config only
+// matter in their latest version. Regoter ticks never wait, see sendTick.
+func overflowPolicy(e IReactorEvent) OverflowPolicy {
+	switch e.(type) {
+	case EventGameTick, EventCfgChanged:
+		return OverflowCoalesce
+	case EventDebugPrint:
+		return OverflowDropNewest
+	}
+	return OverflowBlock
+}
+
+// eventOverflowed wakes up a reactor with coalesced messages aside.
+type eventOverflowed struct{}
+
+type mailbox struct {
+	c    chan ReactorEventMessage
+	id   int
+	name string
+
+	mu sync.Mutex
+	// coalesced messages that did not fit, one per event type
+	overflow []ReactorEventMessage
+	// messages of reactors on the WorkerPool that did not fit, in order, and
+	// their number to read without the lock
+	parked  []ReactorEventMessage
+	nParked int32
+	// the mailbox the reactor waits to send to, and since when
+	blockedOn    *mailbox
+	blockedSince time.Time
+	// tells the WorkerPool about new messages, nil for reactors not on a pool
+	wake func()
+	// the reactor runs on the deterministic Scheduler
+	scheduled bool
+	// the World of the reactor, see WorldOf
+	world *World
+}
+
+// mailboxes of the running reactors by their channel, so senders find them
+var (
+	mailboxesMu sync.RWMutex
+	mailboxes   = map[RcTx]*mailbox{}
+	mailboxIds  int
+)
+
+func newMailbox(capacity int) *mailbox {
+	return &mailbox{c: make(chan ReactorEventMessage, capacity)}
+}
+
+// open makes mb known to senders as the mailbox of t in w, once.
+func (mb *mailbox) open(t IProcessMessage, w *World) {
+	mailboxesMu.Lock()
+	defer mailboxesMu.Unlock()
+	if mailboxes[mb.c] == mb {
+		return
+	}
+	mb.world = w
+	mailboxIds++
+	mb.id = mailboxIds
+	mb.name = fmt.Sprintf("%v#%v", strings.TrimPrefix(fmt.Sprintf("%T", t), "*model."), mb.id)
+	mailboxes[mb.c] = mb
+}
+
+func (mb *mailbox) close() {
+	mailboxesMu.Lock()
+	defer mailboxesMu.Unlock()
+	delete(mailboxes, mb.c)
+}
+
+func findMailbox(tx RcTx) *mailbox {
+	if tx == nil {
+		return nil
+	}
+	mailboxesMu.RLock()
+	defer mailboxesMu.RUnlock()
+	return mailboxes[tx]
+}
+
+// send sends m to tx, applying the overflow policy of its event if the mailbox is full.
+func send(tx RcTx, m ReactorEventMessage) {
+	sendWith(tx, m, overflowPolicy(m.event))
+}
+
+// sendWith sends m to tx, applying policy if the mailbox is full.
+// Channels without a mailbox, like those of futures, always block.
+func sendWith(tx RcTx, m ReactorEventMessage, policy OverflowPolicy) {
+	to := findMailbox(tx)
+	defer to.notify()
+	if policy == OverflowBlock && to.parks(m.sender) {
+		to.park(m)
+		return
+	}
+	select {
+	case tx <- m:
+		return
+	default:
+	}
+	if to == nil {
+		tx <- m
+		return
+	}
+	switch policy {
+	case OverflowDropNewest:
+	case OverflowDropOldest:
+		for {
+			select {
+			case to.c <- m:
+				return
+			default:
+			}
+			select {
+			case <-to.c:
+			default:
+			}
+		}
+	case OverflowCoalesce:
+		to.coalesce(m)
+	default:
+		from := findMailbox(m.sender)
+		from.block(to)
+		to.c <- m
+		from.block(nil)
+	}
+}
+
+// parks tells whether messages of sender to mb are parked instead of waiting
+// for room. A worker of the pool waiting would hold up the reactors queued
+// after the sender, the deterministic scheduler would wait for itself.
+func (mb *mailbox) parks(sender RcTx) bool {
+	if mb == nil {
+		return false
+	}
+	if mb.scheduled {
+		return true
+	}
+	from := findMailbox(sender)
+	return from != nil && from.wake != nil
+}
+
+// coalesce puts m aside in place of a message of its type the reactor did not
+// handle yet, it returns true if it replaced one.
+func (mb *mailbox) coalesce(m ReactorEventMessage) bool {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	if mb.replaceAside(m) {
+		return true
+	}
+	mb.overflow = append(mb.overflow, m)
+	// the reactor may have emptied its mailbox meanwhile, if it is still full
+	// the reactor takes the overflow after its next message
+	select {
+	case mb.c <- ReactorEventMessage{nil, eventOverflowed{}}:
+	default:
+	}
+	return false
+}
+
+// park sends m without waiting, it is kept aside while the mailbox is full and
+// sent when the reactor of mb handled a message. Messages never pass the ones
+// parked before them.
+func (mb *mailbox) park(m ReactorEventMessage) {
+	if atomic.LoadInt32(&mb.nParked) == 0 {
+		select {
+		case mb.c <- m:
+			return
+		default:
+		}
+	}
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.parked = append(mb.parked, m)
+	// the reactor may have emptied its mailbox meanwhile and waits for a message
+	mb.sendParked()
+}
+
+// flushParked sends the parked messages that fit into the mailbox.
+func (mb *mailbox) flushParked() {
+	if atomic.LoadInt32(&mb.nParked) == 0 {
+		return
+	}
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.sendParked()
+}
+
+func (mb *mailbox) sendParked() {
+	sent := 0
+	for _, m := range mb.parked {
+		select {
+		case mb.c <- m:
+			sent++
+			continue
+		default:
+		}
+		break
+	}
+	mb.parked = append(mb.parked[:0], mb.parked[sent:]...)
+	atomic.StoreInt32(&mb.nParked, int32(len(mb.parked)))
+}
+
+// replace puts m in place of a message of its type kept aside, it returns
+// false if there is none.
+func (mb *mailbox) replace(m ReactorEventMessage) bool {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	return mb.replaceAside(m)
+}
+
+func (mb *mailbox) replaceAside(m ReactorEventMessage) bool {
+	for i, o := range mb.overflow {
+		if reflect.TypeOf(o.event) == reflect.TypeOf(m.event) {
+			mb.overflow[i] = m
+			return true
+		}
+	}
+	return false
+}
+
+// notify wakes the reactor of mb if it runs on a WorkerPool.
+func (mb *mailbox) notify() {
+	if mb != nil && mb.wake != nil {
+		mb.wake()
+	}
+}
+
+func (mb *mailbox) takeOverflow() []ReactorEventMessage {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	overflow := mb.overflow
+	mb.overflow = nil
+	return overflow
+}
+
+// block records that the reactor of mb waits to send to the mailbox to, nil when done.
+func (mb *mailbox) block(to *mailbox) {
+	if mb == nil {
+		return
+	}
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.blockedOn = to
+	mb.blockedSince = time.Now()
+}
+
+func (mb *mailbox) blocked() (*mailbox, time.Time) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	return mb.blockedOn, mb.blockedSince
+}
+
+// Deadlock is a cycle of reactors, each waiting to send to the next one.
+type Deadlock struct {
+	Reactors []string
+}
+
+func (d Deadlock) Error() string {
+	cycle := append(append([]string{}, d.Reactors...), d.Reactors[0])
+	return "deadlock: " + strings.Join(cycle, " -> ")
+}
+
+// StartWatchdog looks every interval for reactors blocked sending to each
+// other for longer than interval and reports each deadlock once.
+// Call the returned function to stop it.
+func StartWatchdog(interval time.Duration, report func(Deadlock)) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		reported := map[string]bool{}
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				for _, d := range findDeadlocks(now.Add(-interval)) {
+					if !reported[d.Error()] {
+						reported[d.Error()] = true
+						report(d)
+					}
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// findDeadlocks returns the cycles of reactors blocked since before.
+func findDeadlocks(before time.Time) []Deadlock {
+	waits := map[*mailbox]*mailbox{}
+	var blocked []*mailbox
+	mailboxesMu.RLock()
+	for _, mb := range mailboxes {
+		if to, since := mb.blocked(); to != nil && since.Before(before) {
+			waits[mb] = to
+			blocked = append(blocked, mb)
+		}
+	}
+	mailboxesMu.RUnlock()
+	sort.Slice(blocked, func(i, j int) bool { return blocked[i].id < blocked[j].id })
+
+	// each reactor waits for one other, so following the waits from any
+	// reactor ends or runs into a cycle
+	var deadlocks []Deadlock
+	seen := map[*mailbox]bool{}
+	for _, start := range blocked {
+		var path []*mailbox
+		onPath := map[*mailbox]int{}
+		mb := start
+		for ; mb != nil && !seen[mb]; mb = waits[mb] {
+			seen[mb] = true
+			onPath[mb] = len(path)
+			path = append(path, mb)
+		}
+		i, ok := onPath[mb]
+		if mb == nil || !ok {
+			continue
+		}
+		cycle := path[i:]
+		first := 0
+		for j, c := range cycle {
+			if c.id < cycle[first].id {
+				first = j
+			}
+		}
+		d := Deadlock{}
+		for j := range cycle {
+			d.Reactors = append(d.Reactors, cycle[(first+j)%len(cycle)].name)
+		}
+		deadlocks = append(deadlocks, d)
+	}
+	return deadlocks
+}
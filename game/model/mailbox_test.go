@@ -0,0 +1,181 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// recorder records the numbered messages it handles.
+type recorder struct {
+	Reactor
+	handled []string
+}
+
+func (r *recorder) ProcessMessage(m ReactorEventMessage) error {
+	if e, ok := m.event.(EventDebugPrint); ok {
+		r.handled = append(r.handled, e.DebugString)
+	}
+	return nil
+}
+
+// newRecorder returns a recorder with a mailbox of capacity messages that
+// senders find, but that nobody handles unless the test does.
+func newRecorder(t *testing.T, capacity int) *recorder {
+	r := &recorder{Reactor: NewReactorWithCapacity(capacity)}
+	r.mailbox.open(r, nil)
+	t.Cleanup(r.mailbox.close)
+	return r
+}
+
+func numbered(n int) ReactorEventMessage {
+	return ReactorEventMessage{nil, EventDebugPrint{DebugString: strconv.Itoa(n)}}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	for _, c := range []struct {
+		policy OverflowPolicy
+		want   []string
+	}{
+		{OverflowDropNewest, []string{"1"}},
+		{OverflowDropOldest, []string{"3"}},
+		{OverflowCoalesce, []string{"1", "3"}},
+	} {
+		r := newRecorder(t, 1)
+		for n := 1; n <= 3; n++ {
+			sendWith(r.tx, numbered(n), c.policy)
+		}
+		r.handle(r, <-r.rx)
+		if len(r.rx) != 0 || !reflect.DeepEqual(r.handled, c.want) {
+			t.Errorf("policy %v: handled %v with %v left, want %v", c.policy, r.handled, len(r.rx), c.want)
+		}
+	}
+}
+
+func TestCoalescedMessageWakesIdleReactor(t *testing.T) {
+	r := newRecorder(t, 1)
+	r.tx <- numbered(1)
+	// the reactor empties its mailbox after the sender found it full
+	<-r.rx
+	r.mailbox.coalesce(numbered(2))
+	r.handle(r, <-r.rx)
+	if !reflect.DeepEqual(r.handled, []string{"2"}) {
+		t.Errorf("handled %v, want [2]", r.handled)
+	}
+}
+
+func TestWatchdogReportsDeadlock(t *testing.T) {
+	a, b := newRecorder(t, 1), newRecorder(t, 1)
+	a.tx <- numbered(1)
+	b.tx <- numbered(1)
+	go sendWith(b.tx, ReactorEventMessage{a.tx, EventGameTick{}}, OverflowBlock)
+	go sendWith(a.tx, ReactorEventMessage{b.tx, EventGameTick{}}, OverflowBlock)
+	// let both go on after the test
+	defer func() { <-a.rx; <-b.rx }()
+
+	deadlocks := make(chan Deadlock, 1)
+	stop := StartWatchdog(10*time.Millisecond, func(d Deadlock) { deadlocks <- d })
+	defer stop()
+	select {
+	case d := <-deadlocks:
+		if len(d.Reactors) != 2 || d.Reactors[0] != a.mailbox.name || d.Reactors[1] != b.mailbox.name {
+			t.Errorf("got %v, want %v and %v", d, a.mailbox.name, b.mailbox.name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no deadlock reported")
+	}
+}
+
+// registerRecorder registers a recorder that never handles its messages with a headless Core.
+func registerRecorder(t *testing.T, capacity int) (RcTx, *recorder, ID) {
+	t.Helper()
+	coreTx, start := newHeadlessTestCore(t)
+	r := newRecorder(t, capacity)
+	id := <-IdGen
+	coreTx <- ReactorEventMessage{r.tx, EventRegisterRegoter{r.tx,
+		RegoterData{Entity: Entity{RgId: id, RgType: RegoterEnumSprite, Position: Position{X: start.X, Y: start.Y}}}, nil}}
+	return coreTx, r, id
+}
+
+func TestSlowRegoterSkipsTicks(t *testing.T) {
+	coreTx, r, id := registerRecorder(t, 2)
+	for i := 0; i < 4; i++ {
+		coreTx <- ReactorEventMessage{nil, EventGameTick{}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stats, err := Ask[EventTickStats](ctx, coreTx, EventAskTickStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Ticks != 4 || stats.Skipped != 2 || stats.SkippedBy[id] != 2 {
+		t.Errorf("got %+v, want 4 ticks with 2 skipped by %v", stats, id)
+	}
+	// the config and the first tick fill the mailbox, the last tick waits aside
+	if len(r.rx) != 2 || len(r.mailbox.overflow) != 1 {
+		t.Errorf("got %v messages waiting and %v aside, want 2 and 1", len(r.rx), len(r.mailbox.overflow))
+	}
+}
+
+func TestTickComesAfterQueuedMessages(t *testing.T) {
+	coreTx, r, id := registerRecorder(t, defaultRegoterMailboxCapacity)
+	coreTx <- ReactorEventMessage{nil, EventDamagePeer{peer: id, damage: 1}}
+	coreTx <- ReactorEventMessage{nil, EventGameTick{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := Ask[EventTickStats](ctx, coreTx, EventAskTickStats{}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for len(r.rx) > 0 {
+		got = append(got, fmt.Sprintf("%T", (<-r.rx).event))
+	}
+	want := []string{"model.EventCfgChanged", "model.EventHealthChange", "model.EventUpdateTick"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestConfigCapacity(t *testing.T) {
+	defer viper.Set("mailbox.regoter", nil)
+	for _, c := range []struct {
+		set, want int
+	}{
+		{5, 5},
+		{1, 1},
+		{0, defaultRegoterMailboxCapacity},
+		{-3, defaultRegoterMailboxCapacity},
+	} {
+		viper.Set("mailbox.regoter", c.set)
+		if got := configCapacity("mailbox.regoter", defaultRegoterMailboxCapacity); got != c.want {
+			t.Errorf("set %v: got %v, want %v", c.set, got, c.want)
+		}
+	}
+}
+
+func TestWorldMailboxCapacities(t *testing.T) {
+	small := NewWorld(GameCfg{MailboxCapacity: 3, CoreMailboxCapacity: 5})
+	var none *World
+	for _, c := range []struct {
+		r          Reactor
+		capacity   int
+		reactorFor string
+	}{
+		{small.NewReactor(), 3, "regoter of the small World"},
+		{small.newReactorCore(), 5, "Core of the small World"},
+		{NewWorld(GameCfg{}).NewReactor(), defaultRegoterMailboxCapacity, "regoter of a default World"},
+		{NewWorld(GameCfg{}).newReactorCore(), defaultCoreMailboxCapacity, "Core of a default World"},
+		{none.NewReactor(), defaultRegoterMailboxCapacity, "regoter of no World"},
+	} {
+		if got := cap(c.r.rx); got != c.capacity {
+			t.Errorf("%v: got capacity %v, want %v", c.reactorFor, got, c.capacity)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package model
 import (
 	"fmt"
 	"image/color"
-	"os"
 
 	"github.com/ebitenui/ebitenui/image"
 	"github.com/ebitenui/ebitenui/widget"
@@ -50,7 +49,7 @@ func gamePage(menu *DemoMenu) *page {
 			widget.ButtonOpts.Image(res.button.image),
 			widget.ButtonOpts.Text("Exit", res.button.face, res.button.text),
 			widget.ButtonOpts.TextPadding(res.button.padding),
-			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) { exit(0) }),
+			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) { menu.game.exit() }),
 		)
 		c.AddChild(exit)
 	}
@@ -580,8 +579,3 @@ func (p *pageContainer) setPage(page *page) {
 	p.flipBook.SetPage(page.content)
 	p.flipBook.RequestRelayout()
 }
-
-func exit(rc int) {
-	// TODO: any cleanup?
-	os.Exit(rc)
-}
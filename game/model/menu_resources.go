@@ -2,6 +2,7 @@ package model
 
 import (
 	"image/color"
+	"io/fs"
 	"lintech/rego/game/loader"
 	"strconv"
 
@@ -40,8 +41,8 @@ const (
 )
 
 const (
-	fontFaceRegular = "resources/menu/fonts/NotoSans-Regular.ttf"
-	fontFaceBold    = "resources/menu/fonts/NotoSans-Bold.ttf"
+	fontFaceRegular = "menu/fonts/NotoSans-Regular.ttf"
+	fontFaceBold    = "menu/fonts/NotoSans-Bold.ttf"
 )
 
 type uiResources struct {
@@ -298,7 +299,7 @@ func loadFonts() (*fonts, error) {
 }
 
 func loadFont(path string, size float64) (font.Face, error) {
-	fontData, err := loader.Embedded.ReadFile(path)
+	fontData, err := fs.ReadFile(loader.Assets, path)
 	if err != nil {
 		return nil, err
 	}
@@ -348,25 +349,25 @@ func loadImageNineSlice(path string, centerWidth int, centerHeight int) (*image.
 }
 
 func newButtonResources(fonts *fonts) (*buttonResources, error) {
-	idle, err := loadImageNineSlice("resources/menu/ui/button-idle.png", 12, 0)
+	idle, err := loadImageNineSlice("menu/ui/button-idle.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	hover, err := loadImageNineSlice("resources/menu/ui/button-hover.png", 12, 0)
+	hover, err := loadImageNineSlice("menu/ui/button-hover.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
-	pressed_hover, err := loadImageNineSlice("resources/menu/ui/button-selected-hover.png", 12, 0)
+	pressed_hover, err := loadImageNineSlice("menu/ui/button-selected-hover.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
-	pressed, err := loadImageNineSlice("resources/menu/ui/button-pressed.png", 12, 0)
+	pressed, err := loadImageNineSlice("menu/ui/button-pressed.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	disabled, err := loadImageNineSlice("resources/menu/ui/button-disabled.png", 12, 0)
+	disabled, err := loadImageNineSlice("menu/ui/button-disabled.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -397,32 +398,32 @@ func newButtonResources(fonts *fonts) (*buttonResources, error) {
 }
 
 func newCheckboxResources() (*checkboxResources, error) {
-	idle, err := loadImageNineSlice("resources/menu/ui/checkbox-idle.png", 20, 0)
+	idle, err := loadImageNineSlice("menu/ui/checkbox-idle.png", 20, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	hover, err := loadImageNineSlice("resources/menu/ui/checkbox-hover.png", 20, 0)
+	hover, err := loadImageNineSlice("menu/ui/checkbox-hover.png", 20, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	disabled, err := loadImageNineSlice("resources/menu/ui/checkbox-disabled.png", 20, 0)
+	disabled, err := loadImageNineSlice("menu/ui/checkbox-disabled.png", 20, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	checked, err := loadGraphicImages("resources/menu/ui/checkbox-checked-idle.png", "resources/menu/ui/checkbox-checked-disabled.png")
+	checked, err := loadGraphicImages("menu/ui/checkbox-checked-idle.png", "menu/ui/checkbox-checked-disabled.png")
 	if err != nil {
 		return nil, err
 	}
 
-	unchecked, err := loadGraphicImages("resources/menu/ui/checkbox-unchecked-idle.png", "resources/menu/ui/checkbox-unchecked-disabled.png")
+	unchecked, err := loadGraphicImages("menu/ui/checkbox-unchecked-idle.png", "menu/ui/checkbox-unchecked-disabled.png")
 	if err != nil {
 		return nil, err
 	}
 
-	greyed, err := loadGraphicImages("resources/menu/ui/checkbox-greyed-idle.png", "resources/menu/ui/checkbox-greyed-disabled.png")
+	greyed, err := loadGraphicImages("menu/ui/checkbox-greyed-idle.png", "menu/ui/checkbox-greyed-disabled.png")
 	if err != nil {
 		return nil, err
 	}
@@ -457,22 +458,22 @@ func newLabelResources(fonts *fonts) *labelResources {
 }
 
 func newComboButtonResources(fonts *fonts) (*comboButtonResources, error) {
-	idle, err := loadImageNineSlice("resources/menu/ui/combo-button-idle.png", 12, 0)
+	idle, err := loadImageNineSlice("menu/ui/combo-button-idle.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	hover, err := loadImageNineSlice("resources/menu/ui/combo-button-hover.png", 12, 0)
+	hover, err := loadImageNineSlice("menu/ui/combo-button-hover.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	pressed, err := loadImageNineSlice("resources/menu/ui/combo-button-pressed.png", 12, 0)
+	pressed, err := loadImageNineSlice("menu/ui/combo-button-pressed.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	disabled, err := loadImageNineSlice("resources/menu/ui/combo-button-disabled.png", 12, 0)
+	disabled, err := loadImageNineSlice("menu/ui/combo-button-disabled.png", 12, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -484,7 +485,7 @@ func newComboButtonResources(fonts *fonts) (*comboButtonResources, error) {
 		Disabled: disabled,
 	}
 
-	arrowDown, err := loadGraphicImages("resources/menu/ui/arrow-down-idle.png", "resources/menu/ui/arrow-down-disabled.png")
+	arrowDown, err := loadGraphicImages("menu/ui/arrow-down-idle.png", "menu/ui/arrow-down-disabled.png")
 	if err != nil {
 		return nil, err
 	}
@@ -508,37 +509,37 @@ func newComboButtonResources(fonts *fonts) (*comboButtonResources, error) {
 }
 
 func newListResources(fonts *fonts) (*listResources, error) {
-	idle, _, err := loader.NewImageFromFile("resources/menu/ui/list-idle.png")
+	idle, _, err := loader.NewImageFromFile("menu/ui/list-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	disabled, _, err := loader.NewImageFromFile("resources/menu/ui/list-disabled.png")
+	disabled, _, err := loader.NewImageFromFile("menu/ui/list-disabled.png")
 	if err != nil {
 		return nil, err
 	}
 
-	mask, _, err := loader.NewImageFromFile("resources/menu/ui/list-mask.png")
+	mask, _, err := loader.NewImageFromFile("menu/ui/list-mask.png")
 	if err != nil {
 		return nil, err
 	}
 
-	trackIdle, _, err := loader.NewImageFromFile("resources/menu/ui/list-track-idle.png")
+	trackIdle, _, err := loader.NewImageFromFile("menu/ui/list-track-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	trackDisabled, _, err := loader.NewImageFromFile("resources/menu/ui/list-track-disabled.png")
+	trackDisabled, _, err := loader.NewImageFromFile("menu/ui/list-track-disabled.png")
 	if err != nil {
 		return nil, err
 	}
 
-	handleIdle, _, err := loader.NewImageFromFile("resources/menu/ui/slider-handle-idle.png")
+	handleIdle, _, err := loader.NewImageFromFile("menu/ui/slider-handle-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	handleHover, _, err := loader.NewImageFromFile("resources/menu/ui/slider-handle-hover.png")
+	handleHover, _, err := loader.NewImageFromFile("menu/ui/slider-handle-hover.png")
 	if err != nil {
 		return nil, err
 	}
@@ -595,27 +596,27 @@ func newListResources(fonts *fonts) (*listResources, error) {
 }
 
 func newSliderResources() (*sliderResources, error) {
-	idle, _, err := loader.NewImageFromFile("resources/menu/ui/slider-track-idle.png")
+	idle, _, err := loader.NewImageFromFile("menu/ui/slider-track-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	disabled, _, err := loader.NewImageFromFile("resources/menu/ui/slider-track-disabled.png")
+	disabled, _, err := loader.NewImageFromFile("menu/ui/slider-track-disabled.png")
 	if err != nil {
 		return nil, err
 	}
 
-	handleIdle, _, err := loader.NewImageFromFile("resources/menu/ui/slider-handle-idle.png")
+	handleIdle, _, err := loader.NewImageFromFile("menu/ui/slider-handle-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	handleHover, _, err := loader.NewImageFromFile("resources/menu/ui/slider-handle-hover.png")
+	handleHover, _, err := loader.NewImageFromFile("menu/ui/slider-handle-hover.png")
 	if err != nil {
 		return nil, err
 	}
 
-	handleDisabled, _, err := loader.NewImageFromFile("resources/menu/ui/slider-handle-disabled.png")
+	handleDisabled, _, err := loader.NewImageFromFile("menu/ui/slider-handle-disabled.png")
 	if err != nil {
 		return nil, err
 	}
@@ -639,15 +640,15 @@ func newSliderResources() (*sliderResources, error) {
 }
 
 func newProgressBarResources() (*progressBarResources, error) {
-	idle, _, err := loader.NewImageFromFile("resources/menu/ui/progressbar-track-idle.png")
+	idle, _, err := loader.NewImageFromFile("menu/ui/progressbar-track-idle.png")
 	if err != nil {
 		return nil, err
 	}
-	fill_idle, _, err := loader.NewImageFromFile("resources/menu/ui/progressbar-fill-idle.png")
+	fill_idle, _, err := loader.NewImageFromFile("menu/ui/progressbar-fill-idle.png")
 	if err != nil {
 		return nil, err
 	}
-	disabled, _, err := loader.NewImageFromFile("resources/menu/ui/slider-track-disabled.png")
+	disabled, _, err := loader.NewImageFromFile("menu/ui/slider-track-disabled.png")
 	if err != nil {
 		return nil, err
 	}
@@ -667,11 +668,11 @@ func newProgressBarResources() (*progressBarResources, error) {
 	}, nil
 }
 func newPanelResources() (*panelResources, error) {
-	i, err := loadImageNineSlice("resources/menu/ui/panel-idle.png", 10, 10)
+	i, err := loadImageNineSlice("menu/ui/panel-idle.png", 10, 10)
 	if err != nil {
 		return nil, err
 	}
-	t, err := loadImageNineSlice("resources/menu/ui/titlebar-idle.png", 10, 10)
+	t, err := loadImageNineSlice("menu/ui/titlebar-idle.png", 10, 10)
 	if err != nil {
 		return nil, err
 	}
@@ -705,7 +706,7 @@ func newTabBookResources(fonts *fonts) (*tabBookResources, error) {
 }
 
 func newHeaderResources(fonts *fonts) (*headerResources, error) {
-	bg, err := loadImageNineSlice("resources/menu/ui/header.png", 446, 9)
+	bg, err := loadImageNineSlice("menu/ui/header.png", 446, 9)
 	if err != nil {
 		return nil, err
 	}
@@ -726,12 +727,12 @@ func newHeaderResources(fonts *fonts) (*headerResources, error) {
 }
 
 func newTextInputResources(fonts *fonts) (*textInputResources, error) {
-	idle, _, err := loader.NewImageFromFile("resources/menu/ui/text-input-idle.png")
+	idle, _, err := loader.NewImageFromFile("menu/ui/text-input-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	disabled, _, err := loader.NewImageFromFile("resources/menu/ui/text-input-disabled.png")
+	disabled, _, err := loader.NewImageFromFile("menu/ui/text-input-disabled.png")
 	if err != nil {
 		return nil, err
 	}
@@ -761,37 +762,37 @@ func newTextInputResources(fonts *fonts) (*textInputResources, error) {
 }
 
 func newTextAreaResources(fonts *fonts) (*textAreaResources, error) {
-	idle, _, err := loader.NewImageFromFile("resources/menu/ui/list-idle.png")
+	idle, _, err := loader.NewImageFromFile("menu/ui/list-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	disabled, _, err := loader.NewImageFromFile("resources/menu/ui/list-disabled.png")
+	disabled, _, err := loader.NewImageFromFile("menu/ui/list-disabled.png")
 	if err != nil {
 		return nil, err
 	}
 
-	mask, _, err := loader.NewImageFromFile("resources/menu/ui/list-mask.png")
+	mask, _, err := loader.NewImageFromFile("menu/ui/list-mask.png")
 	if err != nil {
 		return nil, err
 	}
 
-	trackIdle, _, err := loader.NewImageFromFile("resources/menu/ui/list-track-idle.png")
+	trackIdle, _, err := loader.NewImageFromFile("menu/ui/list-track-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	trackDisabled, _, err := loader.NewImageFromFile("resources/menu/ui/list-track-disabled.png")
+	trackDisabled, _, err := loader.NewImageFromFile("menu/ui/list-track-disabled.png")
 	if err != nil {
 		return nil, err
 	}
 
-	handleIdle, _, err := loader.NewImageFromFile("resources/menu/ui/slider-handle-idle.png")
+	handleIdle, _, err := loader.NewImageFromFile("menu/ui/slider-handle-idle.png")
 	if err != nil {
 		return nil, err
 	}
 
-	handleHover, _, err := loader.NewImageFromFile("resources/menu/ui/slider-handle-hover.png")
+	handleHover, _, err := loader.NewImageFromFile("menu/ui/slider-handle-hover.png")
 	if err != nil {
 		return nil, err
 	}
@@ -834,7 +835,7 @@ func newTextAreaResources(fonts *fonts) (*textAreaResources, error) {
 }
 
 func newToolTipResources(fonts *fonts) (*toolTipResources, error) {
-	bg, _, err := loader.NewImageFromFile("resources/menu/ui/tool-tip.png")
+	bg, _, err := loader.NewImageFromFile("menu/ui/tool-tip.png")
 	if err != nil {
 		return nil, err
 	}
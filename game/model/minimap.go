@@ -46,19 +46,5 @@ func (g *Core) miniMap() *image.RGBA {
 
 func (g *Core) getMapColor(x, y int) color.RGBA {
 	worldMap := g.mapObj.Level(0)
-	switch worldMap[x][y] {
-	case 0:
-		return color.RGBA{43, 30, 24, 255}
-	case 1:
-		return color.RGBA{100, 89, 73, 255}
-	case 2:
-		return color.RGBA{51, 32, 0, 196}
-	case 3:
-		return color.RGBA{56, 36, 0, 196}
-	case 6:
-		// ebitengine splash logo color!
-		return color.RGBA{219, 86, 32, 255}
-	default:
-		return color.RGBA{255, 194, 32, 255}
-	}
+	return g.manifest.MapColor(worldMap[x][y])
 }
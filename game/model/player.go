@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"image/color"
 	"lintech/rego/game/loader"
 	"log"
@@ -16,14 +17,17 @@ const (
 
 const blessedCounterReset = 120
 
+// playerHealth is the health of the player at the start.
+const playerHealth = 100
+
 type Player struct {
 	Reactor
-	rgData       RegoterData
-	cfg          GameCfg
-	unregistered bool
+	rgData RegoterData
+	cfg    GameCfg
+	bus    *Bus
 
 	health         ICooldownInt
-	mouse          MousePosition
+	input          InputSource
 	CameraZ        float64
 	Moved          bool
 	weapon         RcTx
@@ -33,41 +37,56 @@ type Player struct {
 	// Movement in this tick
 }
 
-func (r *Player) ProcessMessage(m ReactorEventMessage) error {
-	// log.Print(fmt.Sprintf("(%v) recv %T", r.thing.GetData().Entity.RgId, e))
-	switch m.event.(type) {
-	case EventUnregisterConfirmed:
-		r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-	default:
-		if !r.unregistered {
-			switch m.event.(type) {
-			case EventUpdateTick:
-				r.eventHandleUpdateTick(m.sender, m.event.(EventUpdateTick))
-			case EventUnregisterConfirmed:
-				r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-			case EventCfgChanged:
-				r.eventHandleCfgChanged(m.sender, m.event.(EventCfgChanged))
-			case EventCollision:
-				r.eventHandleCollision(m.sender, m.event.(EventCollision))
-			case EventHealthChange:
-				r.eventHandleHealthChange(m.sender, m.event.(EventHealthChange))
-			// case EventInput:
-			// 	r.eventHandleInput(m.sender, m.event.(EventInput))
-			default:
-				r.eventHandleUnknown(m.sender, m.event)
-			}
+// registerHandlers declares the events the Player handles.
+func (r *Player) registerHandlers() {
+	On(&r.Reactor, r.eventHandleUpdateTick)
+	On(&r.Reactor, r.eventHandleCfgChanged)
+	On(&r.Reactor, r.eventHandleCollision)
+	On(&r.Reactor, r.eventHandleHealthChange)
+	On(&r.Reactor, r.eventHandleTriggerEnter)
+	On(&r.Reactor, r.eventHandleTriggerExit)
+	On(&r.Reactor, r.eventHandleAskHealth)
+	On(&r.Reactor, r.eventHandleSaveState)
+	On(&r.Reactor, r.eventHandleRestoreState)
+}
+
+func (r *Player) eventHandleTriggerEnter(sender RcTx, e EventTriggerEnter) {
+}
+
+func (r *Player) eventHandleTriggerExit(sender RcTx, e EventTriggerExit) {
+}
+
+func (r *Player) eventHandleAskHealth(sender RcTx, e EventAskHealth) {
+	send(sender, ReactorEventMessage{r.tx, EventHealth{Health: r.health.get()}})
+}
+
+func (r *Player) eventHandleSaveState(sender RcTx, e EventSaveState) {
+	state := SavedState{Health: r.health.get()}
+	for i, w := range r.weaponSet {
+		if w == r.weaponTemplate {
+			state.Weapon = i
 		}
 	}
-	return nil
+	send(sender, ReactorEventMessage{r.tx, EventStateSaved{RgId: r.rgData.Entity.RgId, State: state}})
+}
+
+func (r *Player) eventHandleRestoreState(sender RcTx, e EventRestoreState) {
+	r.health.set(e.State.Health)
+	if err := r.SelectWeapon(sender, e.State.Weapon); err != nil {
+		log.Printf("Warning: restore state: %v", err)
+	}
 }
 
 func (r *Player) eventHandleHealthChange(sender RcTx, e EventHealthChange) {
 	health := r.health.add(-e.change)
+	if e.change > 0 {
+		r.bus.Publish(r.tx, TopicPlayerDamaged, EventPlayerDamaged{Damage: e.change, Health: health})
+	}
 	if health < 0 {
 		//Game End
 		// m := ReactorEventMessage{r.tx, EventUnregisterRegoter{RgId: r.rgData.Entity.RgId}}
 		// sender <- m
-		// r.unregistered = true
+		// r.Unregistering()
 	}
 }
 
@@ -76,35 +95,38 @@ func (c *Player) eventHandleCollision(sender RcTx, e EventCollision) {
 
 func (r *Player) eventHandleCfgChanged(sender RcTx, e EventCfgChanged) {
 	r.cfg = e.Cfg
+	r.bus = e.Bus
 }
 
-func (r *Player) eventHandleUnknown(sender RcTx, e IReactorEvent) error {
-	log.Fatalf("Unknown event: %T", e)
-	return nil
-}
-
-func NewPlayer(coreTx RcTx) RcTx {
+// NewPlayer creates the player at start, moved by input, or by keyboard and mouse when input is nil.
+func NewPlayer(coreTx RcTx, start loader.PlayerStart, input InputSource) RcTx {
+	if input == nil {
+		input = &ebitenInput{}
+	}
 	entity := Entity{
 		RgId:            <-IdGen,
 		RgType:          RegoterEnumPlayer,
 		RgName:          "Player",
-		Position:        Position{X: 8.5, Y: 3.5, Z: 0},
+		Position:        Position{X: start.X, Y: start.Y, Z: 0},
 		Scale:           1,
-		Angle:           geom.Radians(60.0),
+		Angle:           geom.Radians(start.Angle),
 		Pitch:           0,
 		Velocity:        0,
 		Resistance:      0.1,
 		MapColor:        color.RGBA{0, 255, 0, 255},
 		CollisionRadius: loader.ClipDistance,
 		CollisionHeight: 0.5,
+		CollisionLayer:  CollisionLayerPlayer,
+		CollisionMask:   CollisionLayerWall | CollisionLayerEnemy | CollisionLayerPickup,
 	}
 
 	t := &Player{
-		Reactor: NewReactor(),
+		Reactor: WorldOf(coreTx).NewReactor(),
 		rgData: RegoterData{
 			Entity: entity,
 		},
-		health:         &cooldownInt{counterInit: 60, value: 100},
+		health:         &cooldownInt{counterInit: 60, value: playerHealth},
+		input:          input,
 		CameraZ:        0.5,
 		Moved:          false,
 		weaponSet:      NewWeapons(coreTx),
@@ -115,10 +137,14 @@ func NewPlayer(coreTx RcTx) RcTx {
 	// 	log.Fatal("Invalid nil Img for NewPlayer()")
 	// }
 
-	go t.Reactor.Run(t)
-	m := ReactorEventMessage{t.tx, EventRegisterRegoter{t.tx, t.rgData}}
-	coreTx <- m
-	t.SelectWeapon(coreTx, 0)
+	t.Supervise(coreTx, PolicyRestart)
+	t.registerHandlers()
+	t.Reactor.Start(t)
+	m := ReactorEventMessage{t.tx, EventRegisterRegoter{t.tx, t.rgData, t.done}}
+	send(coreTx, m)
+	if err := t.SelectWeapon(coreTx, 0); err != nil {
+		log.Printf("Warning: NewPlayer: %v", err)
+	}
 	return t.tx
 }
 
@@ -132,31 +158,33 @@ func (p *Player) AddWeapon(w *WeaponTemplate) {
 	p.weaponSet = append(p.weaponSet, w)
 }
 
-func (p *Player) SelectWeapon(coreTx RcTx, index int) {
+// SelectWeapon spawns the weapon of the weapon set at index, an error if
+// there is none.
+func (p *Player) SelectWeapon(coreTx RcTx, index int) error {
 	// TODO: add some kind of sheath/unsheath animation
-	if index < 0 || index > len(p.weaponSet) {
-		log.Fatalf("weaponIndex %v is out of range (0, %v)", index, len(p.weaponSet))
+	if index < 0 || index >= len(p.weaponSet) {
+		return fmt.Errorf("weaponIndex %v is out of range [0, %v)", index, len(p.weaponSet))
 	}
 	newTemplate := p.weaponSet[index]
 	if newTemplate == nil || newTemplate == p.weaponTemplate {
-		return
+		return nil
 	} else {
 		if p.weapon != nil {
 			p.HolsterWeapon(coreTx)
 		}
 		p.weaponTemplate = newTemplate
 		p.weapon = p.weaponTemplate.Spawn(coreTx)
-		return
+		return nil
 	}
 }
 func (p *Player) HolsterWeapon(coreTx RcTx) {
 	m := ReactorEventMessage{p.tx, EventHolsterWeapon{}}
-	p.weapon <- m
+	send(p.weapon, m)
 }
 
 func (p *Player) fireWeapon() {
 	m := ReactorEventMessage{p.tx, EventFireWeapon{}}
-	p.weapon <- m
+	send(p.weapon, m)
 }
 
 func (p *Player) nextWeapon(coreTx RcTx) {
@@ -166,7 +194,9 @@ func (p *Player) nextWeapon(coreTx RcTx) {
 			if w == p.weaponTemplate {
 				ni := (i + 1) % len(p.weaponSet)
 				if ni != i {
-					p.SelectWeapon(coreTx, ni)
+					if err := p.SelectWeapon(coreTx, ni); err != nil {
+						log.Printf("Warning: next weapon: %v", err)
+					}
 					break
 				}
 			}
@@ -209,14 +239,14 @@ func (p *Player) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 	p.rgData.Entity = e.RgEntity
 	p.nextWeaponFlag.cooldown()
 	p.health.cooldown()
-	movement, action := handlePlayerInput(p.cfg, &p.mouse)
+	movement, action := p.input.Input(p.cfg)
 
 	movement.Velocity = p.rgData.Entity.Velocity
 	if !action.KeyPressed {
 		movement.MoveRotate = p.rgData.Entity.LastMoveRotate
 	}
 
-	if action.nextWeapon {
+	if action.NextWeapon {
 		p.nextWeapon(sender)
 	}
 
@@ -225,7 +255,7 @@ func (p *Player) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 		// log.Printf("Moverotate = %.3f", movement.MoveRotate)
 		e := EventMovement{RgId: p.rgData.Entity.RgId, Move: movement}
 		m := ReactorEventMessage{p.tx, e}
-		sender <- m
+		send(sender, m)
 	}
 
 	if action.FireWeapon {
@@ -248,10 +278,6 @@ func (p *Player) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 	// }
 }
 
-func (p *Player) eventHandleUnregisterConfirmed(sender RcTx, e EventUnregisterConfirmed) {
-	p.running = false
-}
-
 // // Move player by move speed in the forward/backward direction
 // func (p *Player) Move(mSpeed float64) {
 // 	p.movement.Acceleration = mSpeed
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// poolBatch is how many messages a reactor handles before its worker moves on.
+const poolBatch = 32
+
+// WorkerPool runs reactors on a fixed number of goroutines instead of one
+// goroutine each. A reactor is queued when a message is sent to it with send,
+// then a worker hands it up to poolBatch messages.
+// Reactors on the pool do not wait for room in a full mailbox, their messages
+// are parked instead, see mailbox.park.
+type WorkerPool struct {
+	mu      sync.Mutex
+	ready   *sync.Cond
+	queue   []*pooledReactor
+	closed  bool
+	workers sync.WaitGroup
+}
+
+type pooledReactor struct {
+	r    *Reactor
+	t    IProcessMessage
+	pool *WorkerPool
+	// 1 while queued or handling messages
+	scheduled int32
+}
+
+// NewWorkerPool returns a WorkerPool of workers goroutines.
+func NewWorkerPool(workers int) *WorkerPool {
+	p := &WorkerPool{}
+	p.ready = sync.NewCond(&p.mu)
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Close ends the workers when no reactor is queued anymore, after the reactors
+// on the pool stopped. Reactors started later run on goroutines of their own.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.ready.Broadcast()
+	p.workers.Wait()
+}
+
+// start runs the reactor on the pool, it returns false if the pool is closed.
+func (p *WorkerPool) start(r *Reactor, t IProcessMessage) bool {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return false
+	}
+	pr := &pooledReactor{r: r, t: t, pool: p}
+	r.mailbox.wake = pr.wake
+	r.started(t)
+	if len(r.rx) > 0 {
+		pr.wake()
+	}
+	return true
+}
+
+func (p *WorkerPool) work() {
+	defer p.workers.Done()
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.ready.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		pr := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+		pr.run()
+	}
+}
+
+// wake queues the reactor unless it is queued or handling messages already.
+func (pr *pooledReactor) wake() {
+	if !atomic.CompareAndSwapInt32(&pr.scheduled, 0, 1) {
+		return
+	}
+	p := pr.pool
+	p.mu.Lock()
+	p.queue = append(p.queue, pr)
+	p.mu.Unlock()
+	p.ready.Signal()
+}
+
+func (pr *pooledReactor) run() {
+	r := pr.r
+	for n := 0; n < poolBatch && r.running; n++ {
+		m, ok := pr.receive()
+		if !ok {
+			break
+		}
+		r.handle(pr.t, m)
+	}
+	if !r.running {
+		// it stays scheduled, so it is never queued again
+		r.stopped(pr.t)
+		return
+	}
+	atomic.StoreInt32(&pr.scheduled, 0)
+	// a message sent while the reactor was scheduled did not wake it
+	if len(r.rx) > 0 {
+		pr.wake()
+	}
+}
+
+func (pr *pooledReactor) receive() (ReactorEventMessage, bool) {
+	select {
+	case m := <-pr.r.rx:
+		return m, true
+	default:
+		return ReactorEventMessage{}, false
+	}
+}
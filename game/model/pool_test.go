@@ -0,0 +1,184 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolRunsReactors(t *testing.T) {
+	w := NewWorld(GameCfg{Workers: 2})
+	defer w.Close()
+	h := &hooked{Reactor: w.NewReactor()}
+	h.Reactor.Start(h)
+	for i := 0; i < 3*poolBatch; i++ {
+		send(h.tx, ReactorEventMessage{nil, EventGameTick{}})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(h.events); got != 3*poolBatch+2 || h.events[0] != "start" || h.events[got-1] != "stop" {
+		t.Errorf("got %v events from %v to %v, want start, %v messages and stop",
+			got, h.events[0], h.events[got-1], 3*poolBatch)
+	}
+}
+
+func TestWorkerPoolStaysInItsWorld(t *testing.T) {
+	pooled := NewWorld(GameCfg{Workers: 1})
+	defer pooled.Close()
+	own := &hooked{Reactor: NewWorld(GameCfg{}).NewReactor()}
+	own.Reactor.Start(own)
+	onPool := &hooked{Reactor: pooled.NewReactor()}
+	onPool.Reactor.Start(onPool)
+
+	if own.mailbox.wake != nil || onPool.mailbox.wake == nil {
+		t.Error("the WorkerPool of one World runs the reactors of another")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, h := range []*hooked{own, onPool} {
+		if err := h.Stop(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// echo answers every tick.
+type echo struct {
+	Reactor
+}
+
+func (e *echo) ProcessMessage(m ReactorEventMessage) error {
+	if _, ok := m.event.(EventUpdateTick); ok {
+		send(m.sender, ReactorEventMessage{e.tx, EventDrawDone{}})
+	}
+	return nil
+}
+
+func startEchoes(w *World, n int) []*echo {
+	echoes := make([]*echo, n)
+	for i := range echoes {
+		echoes[i] = &echo{Reactor: w.NewReactor()}
+		echoes[i].Reactor.Start(echoes[i])
+	}
+	return echoes
+}
+
+func stopEchoes(tb testing.TB, echoes []*echo) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, e := range echoes {
+		if err := e.Stop(ctx); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// gate handles no message until open is closed, then passes the senders on to got.
+type gate struct {
+	Reactor
+	open chan struct{}
+	got  chan RcTx
+}
+
+func (g *gate) ProcessMessage(m ReactorEventMessage) error {
+	<-g.open
+	g.got <- m.sender
+	return nil
+}
+
+func TestWorkerPoolDoesNotWaitForRoom(t *testing.T) {
+	const senders, messages = 4, 3
+	g := &gate{Reactor: NewReactorWithCapacity(1), open: make(chan struct{}),
+		got: make(chan RcTx, senders*messages)}
+	go g.Run(g)
+	w := NewWorld(GameCfg{Workers: 1})
+	defer w.Close()
+	echoes := startEchoes(w, senders+1)
+	defer stopEchoes(t, echoes)
+
+	// the echoes answer the gate, which is full after the first answer
+	for _, e := range echoes[:senders] {
+		for i := 0; i < messages; i++ {
+			send(e.tx, ReactorEventMessage{g.tx, EventUpdateTick{}})
+		}
+	}
+	answers := make(chan ReactorEventMessage, 1)
+	send(echoes[senders].tx, ReactorEventMessage{answers, EventUpdateTick{}})
+	answered := false
+	select {
+	case <-answers:
+		answered = true
+	case <-time.After(time.Second):
+	}
+	close(g.open)
+	if !answered {
+		t.Fatal("the worker waits for room in the mailbox of the gate")
+	}
+
+	got := map[RcTx]int{}
+	for i := 0; i < senders*messages; i++ {
+		select {
+		case tx := <-g.got:
+			got[tx]++
+		case <-time.After(time.Second):
+			t.Fatalf("the gate got %v of %v answers", i, senders*messages)
+		}
+	}
+	for i, e := range echoes[:senders] {
+		if got[e.tx] != messages {
+			t.Errorf("got %v answers from echo %v, want %v", got[e.tx], i, messages)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := g.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// benchmarkModels runs bench with n reactors on goroutines of their own and on a WorkerPool.
+func benchmarkModels(b *testing.B, bench func(b *testing.B, w *World, n int)) {
+	for _, n := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("goroutines/%v", n), func(b *testing.B) {
+			bench(b, NewWorld(GameCfg{}), n)
+		})
+		b.Run(fmt.Sprintf("pool/%v", n), func(b *testing.B) {
+			w := NewWorld(GameCfg{Workers: runtime.GOMAXPROCS(0)})
+			defer w.Close()
+			bench(b, w, n)
+		})
+	}
+}
+
+// BenchmarkTick sends a tick to every reactor and waits for all answers, as a game tick does.
+func BenchmarkTick(b *testing.B) {
+	benchmarkModels(b, func(b *testing.B, w *World, n int) {
+		echoes := startEchoes(w, n)
+		defer stopEchoes(b, echoes)
+		answers := make(chan ReactorEventMessage, n)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, e := range echoes {
+				send(e.tx, ReactorEventMessage{answers, EventUpdateTick{}})
+			}
+			for range echoes {
+				<-answers
+			}
+		}
+	})
+}
+
+// BenchmarkSpawn starts and stops the reactors, as shots do with projectiles and effects.
+func BenchmarkSpawn(b *testing.B) {
+	benchmarkModels(b, func(b *testing.B, w *World, n int) {
+		for i := 0; i < b.N; i++ {
+			stopEchoes(b, startEchoes(w, n))
+		}
+	})
+}
@@ -1,9 +1,8 @@
 package model
 
 import (
+	"fmt"
 	"image/color"
-	"lintech/rego/game/loader"
-	"log"
 
 	"github.com/harbdog/raycaster-go"
 )
@@ -20,56 +19,53 @@ type ProjectileTemplate struct {
 type Projectile struct {
 	Reactor
 	ProjectileTemplate
-	unregistered bool
-}
-
-func (r *Projectile) ProcessMessage(m ReactorEventMessage) error {
-	switch m.event.(type) {
-	case EventUnregisterConfirmed:
-		r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-	default:
-		if !r.unregistered {
-			// log.Print(fmt.Sprintf("(%v) recv %T", r.thing.GetData().Entity.RgId, e))
-			switch m.event.(type) {
-			case EventUpdateTick:
-				r.eventHandleUpdateTick(m.sender, m.event.(EventUpdateTick))
-			case EventCollision:
-				r.eventHandleCollision(m.sender, m.event.(EventCollision))
-			case EventHealthChange:
-				r.eventHandleHealthChange(m.sender, m.event.(EventHealthChange))
-			case EventUnregisterConfirmed:
-				r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-			case EventCfgChanged:
-				r.eventHandleCfgChanged(m.sender, m.event.(EventCfgChanged))
-			default:
-				r.eventHandleUnknown(m.sender, m.event)
-			}
-		}
-	}
-	return nil
 }
 
-func (r *Projectile) eventHandleUnknown(sender RcTx, e IReactorEvent) error {
-	log.Fatalf("Unknown event: %T", e)
-	return nil
+// registerHandlers declares the events the Projectile handles.
+func (r *Projectile) registerHandlers() {
+	On(&r.Reactor, r.eventHandleUpdateTick)
+	OnFallible(&r.Reactor, r.eventHandleCollision)
+	On(&r.Reactor, r.eventHandleHealthChange)
+	On(&r.Reactor, r.eventHandleTriggerEnter)
+	On(&r.Reactor, r.eventHandleTriggerExit)
+	On(&r.Reactor, r.eventHandleCfgChanged)
+	On(&r.Reactor, r.eventHandleSaveState)
+	On(&r.Reactor, r.eventHandleRestoreState)
+}
+
+func (r *Projectile) eventHandleSaveState(sender RcTx, e EventSaveState) {
+	state := SavedState{Lifespan: r.lifespan}
+	send(sender, ReactorEventMessage{r.tx, EventStateSaved{RgId: r.rgData.Entity.RgId, State: state}})
+}
+
+func (r *Projectile) eventHandleRestoreState(sender RcTx, e EventRestoreState) {
+	r.lifespan = e.State.Lifespan
+}
+
+func (r *Projectile) eventHandleTriggerEnter(sender RcTx, e EventTriggerEnter) {
+}
+
+func (r *Projectile) eventHandleTriggerExit(sender RcTx, e EventTriggerExit) {
 }
 
 func (r *Projectile) eventHandleHealthChange(sender RcTx, e EventHealthChange) {
 }
 
-func (c *Projectile) eventHandleCollision(sender RcTx, e EventCollision) {
-	if e.collistion.peer == NULL_ID {
-		log.Fatalf("Info: Try to find NULL_ID(%v) in core", NULL_ID)
+func (c *Projectile) eventHandleCollision(sender RcTx, e EventCollision) error {
+	contact := e.nearest()
+	if contact.peer == NULL_ID {
+		return fmt.Errorf("collision with NULL_ID(%v)", NULL_ID)
 	}
-	if e.collistion.peer != WALL_ID {
-		d := ReactorEventMessage{c.tx, EventDamagePeer{peer: e.collistion.peer, damage: c.harm}}
-		sender <- d
+	if contact.peer != WALL_ID {
+		d := ReactorEventMessage{c.tx, EventDamagePeer{peer: contact.peer, damage: c.harm}}
+		send(sender, d)
 	}
 
 	m := ReactorEventMessage{c.tx, EventUnregisterRegoter{RgId: c.rgData.Entity.RgId}}
-	sender <- m
-	c.unregistered = true
-	c.effect.Spawn(sender, e.collistion.position)
+	send(sender, m)
+	c.Unregistering()
+	c.effect.Spawn(sender, contact.position)
+	return nil
 }
 
 func (c *Projectile) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
@@ -77,20 +73,16 @@ func (c *Projectile) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 
 	if c.lifespan < 0 {
 		m := ReactorEventMessage{c.tx, EventUnregisterRegoter{RgId: c.rgData.Entity.RgId}}
-		sender <- m
+		send(sender, m)
 		c.effect.Spawn(sender, e.RgEntity.Position)
 	} else {
 		m := ReactorEventMessage{c.tx, EventMovement{RgId: c.rgData.Entity.RgId,
 			Move: Movement{Velocity: c.rgData.Entity.Velocity}}}
-		sender <- m
+		send(sender, m)
 	}
 
 }
 
-func (c *Projectile) eventHandleUnregisterConfirmed(sender RcTx, e EventUnregisterConfirmed) {
-	c.running = false
-}
-
 func (p *ProjectileTemplate) Spawn(coreTx RcTx, pt *ProjectileTemplate,
 	parentId ID, position Position, aimAngle float64, aimPitch float64) RcTx {
 	p.playAudio()
@@ -106,7 +98,7 @@ func NewProjectile(coreTx RcTx, pt *ProjectileTemplate, parentId ID, position Po
 	aimAngle float64, aimPitch float64) RcTx {
 
 	p := &Projectile{
-		Reactor:            NewReactor(),
+		Reactor:            WorldOf(coreTx).NewReactor(),
 		ProjectileTemplate: *pt,
 	}
 	// Don't use ID of Template
@@ -115,9 +107,11 @@ func NewProjectile(coreTx RcTx, pt *ProjectileTemplate, parentId ID, position Po
 	p.rgData.Entity.Position = position
 	p.rgData.Entity.Angle = aimAngle
 	p.rgData.Entity.Pitch = aimPitch
-	go p.Reactor.Run(p)
-	m := ReactorEventMessage{p.tx, EventRegisterRegoter{p.tx, p.rgData}}
-	coreTx <- m
+	p.Supervise(coreTx, PolicyUnregister)
+	p.registerHandlers()
+	p.Reactor.Start(p)
+	m := ReactorEventMessage{p.tx, EventRegisterRegoter{p.tx, p.rgData, p.done}}
+	send(coreTx, m)
 	return p.tx
 }
 
@@ -136,6 +130,8 @@ func NewProjectileTemplate(di DrawInfo,
 		Anchor:          raycaster.AnchorCenter,
 		CollisionRadius: collision.CollisionRadius,
 		CollisionHeight: collision.CollisionHeight,
+		CollisionLayer:  collision.CollisionLayer,
+		CollisionMask:   collision.CollisionMask,
 	}
 	t := &ProjectileTemplate{
 		rgData: RegoterData{
@@ -151,13 +147,18 @@ func NewProjectileTemplate(di DrawInfo,
 	return t
 }
 
+// projectiles of the player pass the player and hit walls and enemies
+const playerProjectileMask = CollisionLayerWall | CollisionLayerEnemy
+
 func ProjectileChargedBolt(effect *EffectTemplate) *ProjectileTemplate {
 	// preload projectile sprites
-	chargedBoltImg := loader.GetSpriteFromFile("charged_bolt_sheet.png")
+	chargedBoltSheet := loadSprite("charged_bolt")
+	chargedBoltImg := chargedBoltSheet.Img
 	chargedBoltWidth := chargedBoltImg.Bounds().Dx()
-	chargedBoltCols, chargedBoltRows := 6, 1
+	chargedBoltCols, chargedBoltRows := chargedBoltSheet.Columns, chargedBoltSheet.Rows
 	chargedBoltScale := 0.3
 	di := DrawInfo{
+		Asset:         "charged_bolt",
 		Img:           chargedBoltImg,
 		Columns:       chargedBoltCols,
 		Rows:          chargedBoltRows,
@@ -167,7 +168,8 @@ func ProjectileChargedBolt(effect *EffectTemplate) *ProjectileTemplate {
 	chargedBoltPxRadius := 50.0
 	chargedBoltCollisionRadius := (chargedBoltScale * chargedBoltPxRadius) / (float64(chargedBoltWidth) / float64(chargedBoltCols))
 	chargedBoltCollisionHeight := 2 * chargedBoltCollisionRadius
-	collision := CollisionSpace{chargedBoltCollisionRadius, chargedBoltCollisionHeight}
+	collision := CollisionSpace{chargedBoltCollisionRadius, chargedBoltCollisionHeight,
+		CollisionLayerProjectile, playerProjectileMask}
 	chargedBoltVelocity := 0.5 // Velocity (as distance travelled/second)
 	audioPlayer := LoadAudioPlayer("blaster.mp3")
 	chargedBoltProjectile := NewProjectileTemplate(di,
@@ -178,11 +180,13 @@ func ProjectileChargedBolt(effect *EffectTemplate) *ProjectileTemplate {
 
 func ProjectileRedBolt(effect *EffectTemplate) *ProjectileTemplate {
 	// preload projectile sprites
-	redBoltImg := loader.GetSpriteFromFile("red_bolt.png")
+	redBoltSheet := loadSprite("red_bolt")
+	redBoltImg := redBoltSheet.Img
 	redBoltWidth := redBoltImg.Bounds().Dx()
-	redBoltCols, redBoltRows := 1, 1
+	redBoltCols, redBoltRows := redBoltSheet.Columns, redBoltSheet.Rows
 	redBoltScale := 0.25
 	di := DrawInfo{
+		Asset:         "red_bolt",
 		Img:           redBoltImg,
 		Columns:       redBoltCols,
 		Rows:          redBoltRows,
@@ -192,7 +196,8 @@ func ProjectileRedBolt(effect *EffectTemplate) *ProjectileTemplate {
 	redBoltPxRadius := 4.0
 	redBoltCollisionRadius := (redBoltScale * redBoltPxRadius) / (float64(redBoltWidth) / float64(redBoltCols))
 	redBoltCollisionHeight := 2 * redBoltCollisionRadius
-	collision := CollisionSpace{redBoltCollisionRadius, redBoltCollisionHeight}
+	collision := CollisionSpace{redBoltCollisionRadius, redBoltCollisionHeight,
+		CollisionLayerProjectile, playerProjectileMask}
 	redBoltVelocity := 0.5 // Velocity (as distance travelled/second)
 	audioPlayer := LoadAudioPlayer("jab.wav")
 	redBoltProjectile := NewProjectileTemplate(di,
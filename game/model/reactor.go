@@ -1,11 +1,14 @@
 package model
 
 import (
-	"fmt"
 	"image/color"
+	"lintech/rego/game/loader"
 	"log"
+	"reflect"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/harbdog/raycaster-go/geom3d"
 )
 
 type IReactorEvent interface{}
@@ -19,7 +22,26 @@ type IEventHandler interface {
 type Reactor struct {
 	rx      RcRx
 	tx      RcTx
+	mailbox *mailbox
 	running bool
+	// the World the reactor runs in, nil for a goroutine of its own
+	world *World
+	// closed when the reactor stopped
+	done chan struct{}
+	// gets the failures of the reactor, see Supervise
+	supervisor RcTx
+	policy     SupervisionPolicy
+	// times of the restarts within restartWindow
+	restarts []time.Time
+	// panicked with PolicyUnregister or PolicyEscalate, waiting to be removed
+	failed bool
+	// the failure that stopped the reactor, see Err
+	err error
+	// handlers by event type and for the other events, see On
+	handlers map[reflect.Type]func(RcTx, IReactorEvent) error
+	fallback func(RcTx, IReactorEvent) error
+	// asked Core to unregister it, waiting for the confirmation
+	unregistered bool
 }
 
 type ReactorEventMessage struct {
@@ -81,7 +103,7 @@ type Command struct {
 
 type Action struct {
 	FireWeapon bool
-	nextWeapon bool
+	NextWeapon bool
 	KeyPressed bool
 }
 
@@ -132,6 +154,28 @@ type GameCfg struct {
 	MaxLightRGB        color.NRGBA
 	//
 	RenderFloorTex bool
+	// level file on disk, empty for the embedded demo level
+	Level string
+	// asset directory on disk with a manifest.json, empty for the embedded assets
+	AssetDir string
+	// mod directory and zip pack checked for assets before the embedded ones
+	ModDir    string
+	AssetPack string
+	// reload the level and assets when their files change
+	HotReload bool
+	// run all reactors on one goroutine in a fixed order with random numbers from Seed
+	Deterministic bool
+	Seed          int64
+	// mailbox capacities of regoters and Core, 0 for the default
+	MailboxCapacity     int
+	CoreMailboxCapacity int
+	// run the regoters on a pool of this many goroutines, 0 for one goroutine each
+	Workers int
+	// write the input of the player to the file Record, or play it back from the file Replay
+	Record string
+	Replay string
+	// quicksave and quickload file
+	Snapshot string
 	// Debug option
 	ShowSpriteBoxes bool
 	Debug           bool
@@ -150,7 +194,9 @@ type RegoterState struct {
 }
 
 type DrawInfo struct {
-	ImgLayer          ImgLayer
+	ImgLayer ImgLayer
+	// name of the sprite Img is loaded from
+	Asset             string
 	Img               *ebiten.Image
 	TexFacingMap      *map[float64]int
 	AnimationReversed bool
@@ -170,17 +216,28 @@ type RegoterData struct {
 type CollisionSpace struct {
 	CollisionRadius float64
 	CollisionHeight float64
+	CollisionLayer  CollisionLayer
+	CollisionMask   CollisionLayer
 }
 
 type EntityCollision struct {
 	// entity     *Entity
 	position Position
 	peer     ID
+	// distance moved until the contact
 	distance float64
+	// normal of the surface at the contact, it points against the move
+	normal geom3d.Vector3
 }
 
 type EventCollision struct {
-	collistion EntityCollision
+	// contacts on the way of the move, the nearest first
+	contacts []EntityCollision
+}
+
+// nearest returns the first contact of the move.
+func (e EventCollision) nearest() EntityCollision {
+	return e.contacts[0]
 }
 
 // Events
@@ -199,6 +256,26 @@ type EventHealthChange struct {
 
 type EventCfgChanged struct {
 	Cfg GameCfg
+	// the Bus of the world, set by Core
+	Bus *Bus
+}
+
+// EventAskBus asks Core for the Bus of its world, it answers with EventBus.
+type EventAskBus struct{}
+
+type EventBus struct {
+	Bus *Bus
+}
+
+// EventPlayerDamaged is published on TopicPlayerDamaged.
+type EventPlayerDamaged struct {
+	Damage int
+	Health int
+}
+
+// EventEnemyDied is published on TopicEnemyDied.
+type EventEnemyDied struct {
+	RgId ID
 }
 
 type EventDraw struct {
@@ -222,18 +299,121 @@ type EventUpdateTick struct {
 
 type EventGameTick struct{}
 
+// EventReloadContent asks Core to swap in a changed level and assets.
+type EventReloadContent struct {
+	Map      *loader.Map
+	Manifest *loader.Manifest
+}
+
 type EventRegisterRegoter struct {
 	tx     RcTx
 	RgData RegoterData
+	// Done of the reactor of the regoter, nil if it has none
+	done <-chan struct{}
 }
 
 type EventUnregisterRegoter struct {
 	RgId ID
 }
 
+// EventRegisterTrigger registers a trigger with Core, the sender owns it.
+type EventRegisterTrigger struct {
+	Trigger Trigger
+}
+
+type EventUnregisterTrigger struct {
+	TriggerId ID
+}
+
+// EventTriggerEnter is sent to the owner of a trigger and the entity that entered it.
+type EventTriggerEnter struct {
+	TriggerId ID
+	RgId      ID
+}
+
+// EventTriggerExit is sent to the owner of a trigger and the entity that left it.
+type EventTriggerExit struct {
+	TriggerId ID
+	RgId      ID
+}
+
 type EventUnregisterConfirmed struct {
 }
 
+// EventShutdown asks Core to unregister every regoter and stop, it answers with EventWorldStopped.
+type EventShutdown struct{}
+
+type EventWorldStopped struct {
+	// closed when the regoters and Core stopped
+	Done []<-chan struct{}
+}
+
+// EventReactorFailed reports a Failure of the sender to its supervisor.
+// Policy is what the sender does about a panic, it goes on after an error.
+type EventReactorFailed struct {
+	Failure Failure
+	Policy  SupervisionPolicy
+}
+
+// EventAskHealth asks the player or an enemy for its health, it answers with EventHealth.
+type EventAskHealth struct{}
+
+type EventHealth struct {
+	Health int
+}
+
+// EventAskCellFree asks Core whether a cell has no wall and no solid regoter on the ground,
+// it answers with EventCellFree.
+type EventAskCellFree struct {
+	X, Y int
+}
+
+type EventCellFree struct {
+	Free bool
+}
+
+// EventAskTickStats asks Core how many ticks regoters skipped, it answers with EventTickStats.
+type EventAskTickStats struct{}
+
+type EventTickStats struct {
+	Ticks int
+	// ticks skipped by the registered regoters, in all and by each that skipped any
+	Skipped   int
+	SkippedBy map[ID]int
+}
+
+// EventSaveSnapshot asks Core for a Snapshot of the world, it answers with EventSnapshotSaved.
+type EventSaveSnapshot struct{}
+
+type EventSnapshotSaved struct {
+	Snapshot Snapshot
+}
+
+// EventSaveState asks a regoter for the state it keeps itself, it answers with EventStateSaved.
+type EventSaveState struct{}
+
+type EventStateSaved struct {
+	RgId  ID
+	State SavedState
+}
+
+// EventClearWorld removes the enemies, projectiles and effects before a snapshot of Level is restored.
+type EventClearWorld struct {
+	Level string
+}
+
+// EventRestoreRegoter restores a regoter of a snapshot in Core, Tx is the
+// regoter respawned for it, nil for the player.
+type EventRestoreRegoter struct {
+	Tx    RcTx
+	Saved RegoterSnapshot
+}
+
+// EventRestoreState gives a restored regoter back the state it keeps itself.
+type EventRestoreState struct {
+	State SavedState
+}
+
 type EventMovement struct {
 	RgId    ID
 	Move    Movement
@@ -244,16 +424,16 @@ type EventMovement struct {
 // 	input Movement
 // }
 
+// NewReactor returns a regoter reactor with a mailbox of the default capacity
+// that runs on its own goroutine. Regoters of a game are made with World.NewReactor.
 func NewReactor() Reactor {
-	// NOTE:  If there are about 10,000 Regoters,
-	// 			  this Core routine may not able to recv msg quick enough.
-	//			 	So Regoter will block on sending data to Core and can not receive data.
-	// .      At same time Core are going to send data to this Regoter.
-	//        It will be Dead Lock!!!
-	// 			  So we set Regoter chan buffer size to 100 and keep Core buffer size at 1000.
-	//				So Core will not be blocked on Sending. And Regoter need wait Core.
-	c := make(chan ReactorEventMessage, 100)
-	rc := Reactor{c, c, true}
+	return NewReactorWithCapacity(defaultRegoterMailboxCapacity)
+}
+
+// NewReactorWithCapacity returns a reactor with a mailbox of capacity messages.
+func NewReactorWithCapacity(capacity int) Reactor {
+	mb := newMailbox(capacity)
+	rc := Reactor{rx: mb.c, tx: mb.c, mailbox: mb, running: true, done: make(chan struct{})}
 	return rc
 }
 
@@ -265,26 +445,17 @@ func (r *Reactor) Run(t IProcessMessage) {
 	if r.rx == nil || r.tx == nil {
 		log.Fatal("Reactor channel is not initialized!")
 	}
-	r.running = true
-	var err error
+	r.started(t)
 	for r.running {
-		msg := <-r.rx
-		err = t.ProcessMessage(msg)
-		if err != nil {
-			fmt.Println(err)
-		}
+		r.handle(t, <-r.rx)
 	}
+	r.stopped(t)
 }
 
+// NewReactorCore returns the Core reactor with a mailbox of the default capacity.
+// Core and regoters send to each other, with full mailboxes on both sides they
+// would wait for each other forever. Core sends ticks without waiting, see
+// sendTick, and StartWatchdog reports the deadlocks left.
 func NewReactorCore() Reactor {
-	// NOTE:  If there are about 10,000 Regoters,
-	// 			  this Core routine may not able to recv msg quick enough.
-	//			 	So Regoter will block on sending data to Core and can not receive data.
-	// .      At same time Core are going to send data to this Regoter.
-	//        It will be Dead Lock!!!
-	// 			  So we set Regoter chan buffer size to 100 and keep Core buffer size at 1000.
-	//				So Core will not be blocked on Sending. And Regoter don't need wait Core.
-	c := make(chan ReactorEventMessage, 1000)
-	rc := Reactor{c, c, true}
-	return rc
+	return NewReactorWithCapacity(defaultCoreMailboxCapacity)
 }
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// A replay file holds a replayHeader line and then one InputFrame line per
+// tick of the player, all as JSON. The run is only the same again with the
+// level and seed of the header and the deterministic scheduler.
+
+type replayHeader struct {
+	Level string `json:"level"`
+	Seed  int64  `json:"seed"`
+}
+
+// InputFrame is the input of the player in one tick.
+type InputFrame struct {
+	Move   Movement `json:"move"`
+	Action Action   `json:"action"`
+}
+
+// InputRecorder passes the input of source on and writes it to a replay file.
+type InputRecorder struct {
+	source InputSource
+	enc    *json.Encoder
+}
+
+// NewInputRecorder writes the header of a replay of level and seed to w and
+// returns an InputRecorder writing the input of source after it.
+// Every tick is written right away, so a replay of a crashed game is complete.
+func NewInputRecorder(source InputSource, w io.Writer, level string, seed int64) (*InputRecorder, error) {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(replayHeader{Level: level, Seed: seed}); err != nil {
+		return nil, err
+	}
+	return &InputRecorder{source: source, enc: enc}, nil
+}
+
+func (r *InputRecorder) Input(cfg GameCfg) (Movement, Action) {
+	move, action := r.source.Input(cfg)
+	if r.enc != nil {
+		if err := r.enc.Encode(InputFrame{Move: move, Action: action}); err != nil {
+			log.Printf("Warning: Stop recording input: %v", err)
+			r.enc = nil
+		}
+	}
+	return move, action
+}
+
+// InputReplay plays the input of a replay file back, one frame per tick.
+// After the last frame the player stands still.
+type InputReplay struct {
+	Level  string
+	Seed   int64
+	frames []InputFrame
+	next   int
+}
+
+func LoadInputReplay(r io.Reader) (*InputReplay, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var header replayHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("replay header: %w", err)
+	}
+	replay := &InputReplay{Level: header.Level, Seed: header.Seed}
+	for {
+		var frame InputFrame
+		err := dec.Decode(&frame)
+		if err == io.EOF {
+			return replay, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay frame %v: %w", len(replay.frames), err)
+		}
+		replay.frames = append(replay.frames, frame)
+	}
+}
+
+func LoadInputReplayFile(path string) (*InputReplay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadInputReplay(f)
+}
+
+func (r *InputReplay) Input(cfg GameCfg) (Movement, Action) {
+	if r.Done() {
+		return Movement{}, Action{}
+	}
+	frame := r.frames[r.next]
+	r.next++
+	return frame.Move, frame.Action
+}
+
+// Done reports whether all frames are played.
+func (r *InputReplay) Done() bool {
+	return r.next >= len(r.frames)
+}
+
+// newInputSource returns the input of the player set in config: keyboard and
+// mouse, recorded to cfg.Record, or played back from cfg.Replay.
+// Recording and replaying need a deterministic run, so they turn it on, and a
+// replay brings its own level and seed.
+func newInputSource(cfg *GameCfg) (InputSource, error) {
+	switch {
+	case cfg.Replay != "":
+		replay, err := LoadInputReplayFile(cfg.Replay)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Level, cfg.Seed, cfg.Deterministic = replay.Level, replay.Seed, true
+		return replay, nil
+	case cfg.Record != "":
+		f, err := os.Create(cfg.Record)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Deterministic = true
+		return NewInputRecorder(&ebitenInput{}, f, cfg.Level, cfg.Seed)
+	}
+	return &ebitenInput{}, nil
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+)
+
+// scriptedInput plays a fixed list of frames.
+type scriptedInput []InputFrame
+
+func (s *scriptedInput) Input(cfg GameCfg) (Movement, Action) {
+	frame := (*s)[0]
+	*s = (*s)[1:]
+	return frame.Move, frame.Action
+}
+
+func TestInputReplayPlaysRecording(t *testing.T) {
+	frames := []InputFrame{
+		{Move: Movement{Acceleration: 0.06}, Action: Action{KeyPressed: true}},
+		{Move: Movement{VissionRotate: 0.015, PitchRotate: -0.005}, Action: Action{FireWeapon: true}},
+		{Action: Action{NextWeapon: true}},
+	}
+	script := scriptedInput(append([]InputFrame{}, frames...))
+
+	var file bytes.Buffer
+	recorder, err := NewInputRecorder(&script, &file, "levels/test.json", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range frames {
+		if move, action := recorder.Input(GameCfg{}); move != want.Move || action != want.Action {
+			t.Fatalf("recorder passed on %+v %+v, want %+v", move, action, want)
+		}
+	}
+
+	replay, err := LoadInputReplay(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Level != "levels/test.json" || replay.Seed != 7 {
+		t.Errorf("got level %q seed %v, want levels/test.json seed 7", replay.Level, replay.Seed)
+	}
+	for i, want := range frames {
+		if move, action := replay.Input(GameCfg{}); move != want.Move || action != want.Action {
+			t.Errorf("tick %v: got %+v %+v, want %+v", i, move, action, want)
+		}
+	}
+	if !replay.Done() {
+		t.Error("replay not done after the last frame")
+	}
+	if move, action := replay.Input(GameCfg{}); move != (Movement{}) || action != (Action{}) {
+		t.Errorf("got %+v %+v after the last frame, want no input", move, action)
+	}
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"log"
+	"math/rand"
+)
+
+// Scheduler runs the reactors on the goroutine calling Settle instead of a
+// goroutine each. Messages are delivered one at a time, going round the
+// reactors in the order they were started, and random draws come from a
+// seeded source. With the same seed and the same input a game then plays the
+// same every time, which regression tests and replays need.
+type Scheduler struct {
+	core     *scheduledReactor
+	reactors []*scheduledReactor
+	rand     *rand.Rand
+}
+
+type scheduledReactor struct {
+	r *Reactor
+	t IProcessMessage
+}
+
+// Start runs the reactor in its World: on its own goroutine, or on the
+// deterministic Scheduler or the WorkerPool if the World has one.
+func (r *Reactor) Start(t IProcessMessage) {
+	s := r.world.Scheduler()
+	if s == nil {
+		if _, ok := t.(*Core); !ok && r.mailbox != nil {
+			if p := r.world.pool(); p != nil && p.start(r, t) {
+				return
+			}
+		}
+		if r.mailbox != nil {
+			// senders and WorldOf find it as soon as Start returns
+			r.mailbox.open(t, r.world)
+		}
+		go r.Run(t)
+		return
+	}
+	if r.rx == nil || r.tx == nil {
+		log.Fatal("Reactor channel is not initialized!")
+	}
+	sr := &scheduledReactor{r, t}
+	if r.mailbox != nil {
+		r.mailbox.scheduled = true
+	}
+	r.started(t)
+	if _, ok := t.(*Core); ok {
+		s.core = sr
+		return
+	}
+	s.reactors = append(s.reactors, sr)
+}
+
+// Settle delivers messages until every reactor is idle.
+// Core gets its messages after each message of another reactor, so its queue does not fill up.
+func (s *Scheduler) Settle() {
+	for {
+		idle := !s.drainCore()
+		// reactors started while delivering are appended and get their turn in this round
+		for i := 0; i < len(s.reactors); i++ {
+			if s.reactors[i].deliver() {
+				idle = false
+				s.drainCore()
+			}
+		}
+
+		running := s.reactors[:0]
+		for _, sr := range s.reactors {
+			if sr.r.running {
+				running = append(running, sr)
+			}
+		}
+		s.reactors = running
+		if idle {
+			return
+		}
+	}
+}
+
+// ProcessNow hands m to Core right away, ahead of the messages waiting for it.
+// Drawing uses it, so a frame never advances the simulation.
+func (s *Scheduler) ProcessNow(m ReactorEventMessage) {
+	s.core.process(m)
+}
+
+func (s *Scheduler) drainCore() bool {
+	delivered := false
+	for s.core != nil && s.core.deliver() {
+		delivered = true
+	}
+	return delivered
+}
+
+// deliver processes the next message of the reactor, it returns false if there is none.
+func (sr *scheduledReactor) deliver() bool {
+	if !sr.r.running {
+		return false
+	}
+	select {
+	case m := <-sr.r.rx:
+		sr.process(m)
+		return true
+	default:
+		return false
+	}
+}
+
+func (sr *scheduledReactor) process(m ReactorEventMessage) {
+	wasRunning := sr.r.running
+	sr.r.handle(sr.t, m)
+	if wasRunning && !sr.r.running {
+		sr.r.stopped(sr.t)
+	}
+}
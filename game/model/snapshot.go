@@ -0,0 +1,276 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+// version of the snapshot file format, raise it when the format changes
+const snapshotVersion = 1
+
+// snapshotTypes are the regoters a snapshot holds, parents before their children.
+// Weapons and crosshairs belong to the player and the game, they are not saved.
+var snapshotTypes = []RegoterEnum{
+	RegoterEnumPlayer,
+	RegoterEnumSprite,
+	RegoterEnumEffect,
+	RegoterEnumProjectile,
+}
+
+// Snapshot is the state of the world: the player, enemies, projectiles and effects.
+type Snapshot struct {
+	Version  int               `json:"version"`
+	Level    string            `json:"level"`
+	Regoters []RegoterSnapshot `json:"regoters"`
+}
+
+type RegoterSnapshot struct {
+	Entity Entity       `json:"entity"`
+	State  RegoterState `json:"state"`
+	// sprite the regoter is drawn with, it picks the actor to respawn
+	Asset string     `json:"asset"`
+	Saved SavedState `json:"saved"`
+}
+
+// SavedState is the state a regoter keeps itself, only the fields of its type are used.
+type SavedState struct {
+	Health   int `json:"health,omitempty"`
+	Lifespan int `json:"lifespan,omitempty"`
+	Weapon   int `json:"weapon,omitempty"`
+}
+
+// snapshotInCore is a snapshot waiting for the regoters to send their state.
+type snapshotInCore struct {
+	requester RcTx
+	snapshot  Snapshot
+	regoters  []*RegoterSnapshot
+	waiting   map[ID]*RegoterSnapshot
+}
+
+func (g *Core) eventHandleSaveSnapshot(sender RcTx, e EventSaveSnapshot) {
+	s := &snapshotInCore{
+		requester: sender,
+		snapshot:  Snapshot{Version: snapshotVersion, Level: g.mapObj.Name()},
+		waiting:   map[ID]*RegoterSnapshot{},
+	}
+	for _, rgType := range snapshotTypes {
+		// in ID order, so snapshots of the same world are the same
+		ids := make([]ID, 0, len(g.rgs[rgType]))
+		for id := range g.rgs[rgType] {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		for _, id := range ids {
+			rg := g.rgs[rgType][id]
+			rs := &RegoterSnapshot{Entity: rg.entity, State: rg.state, Asset: rg.di.Asset}
+			s.regoters = append(s.regoters, rs)
+			s.waiting[id] = rs
+			send(rg.tx, ReactorEventMessage{g.tx, EventSaveState{}})
+		}
+	}
+	g.snapshots = append(g.snapshots, s)
+	g.finishSnapshots()
+}
+
+func (g *Core) eventHandleStateSaved(sender RcTx, e EventStateSaved) {
+	for _, s := range g.snapshots {
+		if rs, ok := s.waiting[e.RgId]; ok {
+			rs.Saved = e.State
+			delete(s.waiting, e.RgId)
+		}
+	}
+	g.finishSnapshots()
+}
+
+// removeFromSnapshots drops a regoter leaving the world from the snapshots waiting for its state.
+func (g *Core) removeFromSnapshots(id ID) {
+	for _, s := range g.snapshots {
+		rs, ok := s.waiting[id]
+		if !ok {
+			continue
+		}
+		delete(s.waiting, id)
+		for i, r := range s.regoters {
+			if r == rs {
+				s.regoters = append(s.regoters[:i], s.regoters[i+1:]...)
+				break
+			}
+		}
+	}
+	g.finishSnapshots()
+}
+
+// finishSnapshots sends the snapshots with the state of all regoters to their requesters.
+func (g *Core) finishSnapshots() {
+	pending := g.snapshots[:0]
+	for _, s := range g.snapshots {
+		if len(s.waiting) > 0 {
+			pending = append(pending, s)
+			continue
+		}
+		s.snapshot.Regoters = make([]RegoterSnapshot, len(s.regoters))
+		for i, rs := range s.regoters {
+			s.snapshot.Regoters[i] = *rs
+		}
+		send(s.requester, ReactorEventMessage{g.tx, EventSnapshotSaved{Snapshot: s.snapshot}})
+	}
+	g.snapshots = pending
+}
+
+func (g *Core) eventHandleClearWorld(sender RcTx, e EventClearWorld) {
+	if e.Level != g.mapObj.Name() {
+		log.Printf("Warning: Restoring a snapshot of level %q in level %q.", e.Level, g.mapObj.Name())
+	}
+	g.cleared = map[ID]bool{}
+	g.restoredIds = map[ID]ID{}
+	for _, rgType := range snapshotTypes {
+		if rgType == RegoterEnumPlayer {
+			continue
+		}
+		for id, rg := range g.rgs[rgType] {
+			g.removeRegoter(g.rgs[rgType], rg)
+			g.cleared[id] = true
+		}
+	}
+}
+
+func (g *Core) eventHandleRestoreRegoter(sender RcTx, e EventRestoreRegoter) {
+	saved := e.Saved.Entity
+	var rg *regoterInCore
+	if saved.RgType == RegoterEnumPlayer {
+		rg = g.getPlayer()
+	} else {
+		rg = g.findRegoterByTx(e.Tx)
+	}
+	if rg == nil {
+		log.Printf("Warning: Can not restore Regoter(%v) %q of the snapshot.", saved.RgId, e.Saved.Asset)
+		return
+	}
+
+	// the restored regoter keeps its ID, the IDs of the snapshot are from another run
+	id := rg.entity.RgId
+	g.restoredIds[saved.RgId] = id
+	rg.entity = saved
+	rg.entity.RgId = id
+	rg.entity.ParentId = g.restoredIds[saved.ParentId]
+	rg.state = e.Saved.State
+	if spatialTypes[rg.rgType] {
+		g.spatial.update(rg)
+	}
+	g.updateTriggers(rg, rg.entity.Position)
+	if rg.rgType == RegoterEnumPlayer {
+		g.updatePlayerCamera(&rg.entity, false, true)
+	}
+	send(rg.tx, ReactorEventMessage{g.tx, EventRestoreState{State: e.Saved.Saved}})
+}
+
+func (g *Core) findRegoterByTx(tx RcTx) *regoterInCore {
+	for _, l := range g.rgs {
+		for _, rg := range l {
+			if rg.tx == tx {
+				return rg
+			}
+		}
+	}
+	return nil
+}
+
+// TakeSnapshot asks Core for a snapshot of the world and waits for it until ctx is done.
+// With the deterministic scheduler it has to be called off the goroutine settling it.
+func TakeSnapshot(ctx context.Context, coreTx RcTx) (Snapshot, error) {
+	e, err := Ask[EventSnapshotSaved](ctx, coreTx, EventSaveSnapshot{})
+	return e.Snapshot, err
+}
+
+// snapshotSpawners respawn the projectiles and effects of a snapshot by their sprite,
+// the enemies are spawned by mapSpawners.
+var snapshotSpawners = map[string]func(coreTx RcTx, rs RegoterSnapshot) RcTx{
+	"charged_bolt": func(coreTx RcTx, rs RegoterSnapshot) RcTx {
+		return spawnSavedProjectile(coreTx, ProjectileChargedBolt(NewBlueExplosionEffect()), rs)
+	},
+	"red_bolt": func(coreTx RcTx, rs RegoterSnapshot) RcTx {
+		return spawnSavedProjectile(coreTx, ProjectileRedBolt(NewRedExplosionEffect()), rs)
+	},
+	"blue_explosion": func(coreTx RcTx, rs RegoterSnapshot) RcTx {
+		return NewEffect(coreTx, NewBlueExplosionEffect(), rs.Entity.Position)
+	},
+	"red_explosion": func(coreTx RcTx, rs RegoterSnapshot) RcTx {
+		return NewEffect(coreTx, NewRedExplosionEffect(), rs.Entity.Position)
+	},
+}
+
+func spawnSavedProjectile(coreTx RcTx, pt *ProjectileTemplate, rs RegoterSnapshot) RcTx {
+	e := rs.Entity
+	return NewProjectile(coreTx, pt, e.ParentId, e.Position, e.Angle, e.Pitch)
+}
+
+// RestoreSnapshot replaces the enemies, projectiles and effects of the world
+// with those of snap and gives the player its saved place and state.
+// It waits for no answer, so it may run on the goroutine settling the
+// deterministic scheduler.
+func RestoreSnapshot(coreTx RcTx, snap *Snapshot) {
+	send(coreTx, ReactorEventMessage{nil, EventClearWorld{Level: snap.Level}})
+	for _, rs := range snap.Regoters {
+		var tx RcTx
+		if rs.Entity.RgType != RegoterEnumPlayer {
+			if spawn, ok := snapshotSpawners[rs.Asset]; ok {
+				tx = spawn(coreTx, rs)
+			} else if spawn, ok := mapSpawners[rs.Asset]; ok {
+				position := rs.Entity.Position
+				tx = spawn(coreTx, &position)
+			} else {
+				log.Printf("Warning: Can not respawn %q of the snapshot.", rs.Asset)
+				continue
+			}
+		}
+		send(coreTx, ReactorEventMessage{nil, EventRestoreRegoter{Tx: tx, Saved: rs}})
+	}
+}
+
+func WriteSnapshot(w io.Writer, snap Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
+
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	snap := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(snap); err != nil {
+		return nil, err
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot version %v, want %v", snap.Version, snapshotVersion)
+	}
+	return snap, nil
+}
+
+func SaveSnapshotFile(path string, snap Snapshot) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteSnapshot(f, snap); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func LoadSnapshotFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	snap, err := ReadSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return snap, nil
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/harbdog/raycaster-go"
+)
+
+func TestSnapshotSavesAndRestores(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	saved := Entity{
+		RgId:     <-IdGen,
+		RgType:   RegoterEnumSprite,
+		Position: Position{X: start.X + 0.5, Y: start.Y, Z: 0.25},
+		Anchor:   raycaster.AnchorBottom,
+		Angle:    1,
+		Velocity: 0.02,
+	}
+	savedRx := make(chan ReactorEventMessage, 100)
+	coreTx <- ReactorEventMessage{savedRx, EventRegisterRegoter{savedRx,
+		RegoterData{Entity: saved, DrawInfo: DrawInfo{Asset: "test"}}, nil}}
+
+	requester := make(chan ReactorEventMessage, 1)
+	coreTx <- ReactorEventMessage{requester, EventSaveSnapshot{}}
+	expectEvent[EventSaveState](t, savedRx)
+	coreTx <- ReactorEventMessage{savedRx, EventStateSaved{RgId: saved.RgId, State: SavedState{Health: 42}}}
+	snap := expectEvent[EventSnapshotSaved](t, requester).Snapshot
+	want := RegoterSnapshot{Entity: saved, State: RegoterState{AnimationRunning: true},
+		Asset: "test", Saved: SavedState{Health: 42}}
+	if len(snap.Regoters) != 1 || snap.Regoters[0] != want {
+		t.Fatalf("got regoters %+v, want %+v", snap.Regoters, want)
+	}
+
+	var file bytes.Buffer
+	if err := WriteSnapshot(&file, snap); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSnapshot(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*read, snap) {
+		t.Fatalf("read %+v, want %+v", *read, snap)
+	}
+
+	// the respawned regoter starts somewhere else and gets a new ID
+	respawned := Entity{RgId: <-IdGen, RgType: RegoterEnumSprite, Position: Position{X: start.X, Y: start.Y}}
+	respawnedRx := make(chan ReactorEventMessage, 100)
+	snapshotSpawners["test"] = func(coreTx RcTx, rs RegoterSnapshot) RcTx {
+		coreTx <- ReactorEventMessage{respawnedRx, EventRegisterRegoter{respawnedRx, RegoterData{Entity: respawned}, nil}}
+		return respawnedRx
+	}
+	t.Cleanup(func() { delete(snapshotSpawners, "test") })
+
+	RestoreSnapshot(coreTx, read)
+	expectEvent[EventUnregisterConfirmed](t, savedRx)
+	if got := expectEvent[EventRestoreState](t, respawnedRx).State; got.Health != 42 {
+		t.Errorf("restored health %v, want 42", got.Health)
+	}
+	coreTx <- ReactorEventMessage{respawnedRx, EventGameTick{}}
+	got := expectEvent[EventUpdateTick](t, respawnedRx).RgEntity
+	want.Entity.RgId = respawned.RgId
+	if got != want.Entity {
+		t.Errorf("restored entity %+v, want %+v", got, want.Entity)
+	}
+}
+
+func TestReadSnapshotChecksVersion(t *testing.T) {
+	if _, err := ReadSnapshot(bytes.NewBufferString(`{"version": 999}`)); err == nil {
+		t.Error("read a snapshot of an unknown version")
+	}
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"math"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// cell size of the spatial hash in map units
+const spatialCellSize = 1.0
+
+// spatialTypes are the regoters placed in the world and kept in the spatial hash.
+var spatialTypes = [len(allRegoterEnum)]bool{
+	RegoterEnumSprite:     true,
+	RegoterEnumProjectile: true,
+	RegoterEnumEffect:     true,
+	RegoterEnumPlayer:     true,
+}
+
+type spatialCell struct {
+	x, y int
+}
+
+// spatialHash buckets regoters by the grid cell of their position, so collision
+// and range queries only look at the regoters in nearby cells.
+type spatialHash struct {
+	cells map[spatialCell]map[ID]*regoterInCore
+	// cell and collision radius each regoter is in the hash with
+	where map[ID]spatialPlace
+	// regoters by collision radius, to find the largest one again when its last regoter leaves
+	radii map[float64]int
+	// largest collision radius, a regoter can reach this far into the neighbouring cells
+	maxRadius float64
+}
+
+type spatialPlace struct {
+	cell   spatialCell
+	radius float64
+}
+
+func newSpatialHash() *spatialHash {
+	return &spatialHash{
+		cells: map[spatialCell]map[ID]*regoterInCore{},
+		where: map[ID]spatialPlace{},
+		radii: map[float64]int{},
+	}
+}
+
+func spatialCellAt(x, y float64) spatialCell {
+	return spatialCell{int(math.Floor(x / spatialCellSize)), int(math.Floor(y / spatialCellSize))}
+}
+
+func (h *spatialHash) insert(rg *regoterInCore) {
+	id := rg.entity.RgId
+	c := spatialCellAt(rg.entity.Position.X, rg.entity.Position.Y)
+	if h.cells[c] == nil {
+		h.cells[c] = map[ID]*regoterInCore{}
+	}
+	h.cells[c][id] = rg
+	radius := rg.entity.CollisionRadius
+	h.where[id] = spatialPlace{c, radius}
+	h.radii[radius]++
+	h.maxRadius = math.Max(h.maxRadius, radius)
+}
+
+func (h *spatialHash) remove(id ID) {
+	place, ok := h.where[id]
+	if !ok {
+		return
+	}
+	c := place.cell
+	delete(h.cells[c], id)
+	if len(h.cells[c]) == 0 {
+		delete(h.cells, c)
+	}
+	delete(h.where, id)
+
+	h.radii[place.radius]--
+	if h.radii[place.radius] > 0 {
+		return
+	}
+	delete(h.radii, place.radius)
+	if place.radius == h.maxRadius {
+		// there are few different radii, most regoters share the radius of their kind
+		h.maxRadius = 0
+		for r := range h.radii {
+			h.maxRadius = math.Max(h.maxRadius, r)
+		}
+	}
+}
+
+// update moves rg to the cell of its current position.
+func (h *spatialHash) update(rg *regoterInCore) {
+	place, ok := h.where[rg.entity.RgId]
+	if ok && place.cell == spatialCellAt(rg.entity.Position.X, rg.entity.Position.Y) &&
+		place.radius == rg.entity.CollisionRadius {
+		return
+	}
+	h.remove(rg.entity.RgId)
+	h.insert(rg)
+}
+
+// queryRect calls fn for the regoters in the cells overlapping the rectangle,
+// grown by the largest collision radius. fn has to check the exact distance.
+func (h *spatialHash) queryRect(minX, minY, maxX, maxY float64, fn func(*regoterInCore)) {
+	lo := spatialCellAt(minX-h.maxRadius, minY-h.maxRadius)
+	hi := spatialCellAt(maxX+h.maxRadius, maxY+h.maxRadius)
+	for x := lo.x; x <= hi.x; x++ {
+		for y := lo.y; y <= hi.y; y++ {
+			for _, rg := range h.cells[spatialCell{x, y}] {
+				fn(rg)
+			}
+		}
+	}
+}
+
+// regotersInRadius returns the regoters in the world with their position
+// within radius of (x, y).
+func (g *Core) regotersInRadius(x, y, radius float64) []*regoterInCore {
+	var found []*regoterInCore
+	g.spatial.queryRect(x-radius, y-radius, x+radius, y+radius, func(rg *regoterInCore) {
+		dx, dy := rg.entity.Position.X-x, rg.entity.Position.Y-y
+		if dx*dx+dy*dy <= radius*radius {
+			found = append(found, rg)
+		}
+	})
+	return found
+}
+
+// regotersNearLine returns the regoters on the collision layers of mask that may
+// be within radius of line, the caller checks the exact intersection.
+func (g *Core) regotersNearLine(line geom.Line, radius float64, mask CollisionLayer) []*regoterInCore {
+	var found []*regoterInCore
+	g.spatial.queryRect(
+		math.Min(line.X1, line.X2)-radius, math.Min(line.Y1, line.Y2)-radius,
+		math.Max(line.X1, line.X2)+radius, math.Max(line.Y1, line.Y2)+radius,
+		func(rg *regoterInCore) {
+			if rg.entity.collisionLayer()&mask != 0 {
+				found = append(found, rg)
+			}
+		})
+	return found
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// spatialTestCore returns a Core with n regoters spread over a few cells,
+// some of them on cell borders.
+func spatialTestCore(n int) (*Core, []*regoterInCore) {
+	g := newCore(GameCfg{})
+	rnd := rand.New(rand.NewSource(1))
+	radii := []float64{0, 0.2, 0.5, 1.5}
+	rgs := make([]*regoterInCore, n)
+	for i := range rgs {
+		x, y := rnd.Float64()*8-3, rnd.Float64()*8-3
+		if i%4 == 0 {
+			x = math.Round(x)
+		}
+		rgs[i] = &regoterInCore{rgType: RegoterEnumSprite, entity: Entity{
+			RgId:            ID(i + 1),
+			Position:        Position{X: x, Y: y},
+			CollisionRadius: radii[i%len(radii)],
+			CollisionLayer:  CollisionLayer(1 << (i % 3)),
+		}}
+		g.spatial.insert(rgs[i])
+	}
+	return g, rgs
+}
+
+func spatialIds(rgs []*regoterInCore) []ID {
+	ids := []ID{}
+	for _, rg := range rgs {
+		ids = append(ids, rg.entity.RgId)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// segmentDistance returns the distance of (x, y) to line.
+func segmentDistance(line geom.Line, x, y float64) float64 {
+	dx, dy := line.X2-line.X1, line.Y2-line.Y1
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((x-line.X1)*dx+(y-line.Y1)*dy)/l))
+	}
+	return math.Hypot(line.X1+t*dx-x, line.Y1+t*dy-y)
+}
+
+// checkSpatialQueries compares the queries of g against a scan of the regoters in rgs.
+func checkSpatialQueries(t *testing.T, g *Core, rgs []*regoterInCore) {
+	t.Helper()
+	for _, c := range []struct {
+		x, y, radius float64
+	}{
+		{0, 0, 0.5},
+		{1, 1, 1},
+		{-0.5, 2.5, 2},
+		{3, -1, 0.99},
+		{2.5, 2.5, 0},
+	} {
+		var want []*regoterInCore
+		for _, rg := range rgs {
+			if math.Hypot(rg.entity.Position.X-c.x, rg.entity.Position.Y-c.y) <= c.radius {
+				want = append(want, rg)
+			}
+		}
+		got, wantIds := spatialIds(g.regotersInRadius(c.x, c.y, c.radius)), spatialIds(want)
+		if len(got) != len(wantIds) {
+			t.Errorf("radius %v around (%v, %v): got %v, want %v", c.radius, c.x, c.y, got, wantIds)
+			continue
+		}
+		for i := range got {
+			if got[i] != wantIds[i] {
+				t.Errorf("radius %v around (%v, %v): got %v, want %v", c.radius, c.x, c.y, got, wantIds)
+				break
+			}
+		}
+	}
+
+	for _, c := range []struct {
+		line   geom.Line
+		radius float64
+		mask   CollisionLayer
+	}{
+		{geom.Line{X1: -2.5, Y1: -2.5, X2: 4.5, Y2: 4.5}, 0.1, 1},
+		{geom.Line{X1: 0, Y1: 1, X2: 3, Y2: 1}, 0.5, 3},
+		{geom.Line{X1: 2, Y1: -3, X2: 2, Y2: 0}, 0, 7},
+		{geom.Line{X1: 1.5, Y1: 1.5, X2: 1.5, Y2: 1.5}, 0.3, 4},
+	} {
+		got := map[ID]bool{}
+		for _, rg := range g.regotersNearLine(c.line, c.radius, c.mask) {
+			if rg.entity.CollisionLayer&c.mask == 0 {
+				t.Errorf("line %+v: got Regoter(%v) not on mask %v", c.line, rg.entity.RgId, c.mask)
+			}
+			got[rg.entity.RgId] = true
+		}
+		// the candidates hold every regoter touching the line
+		for _, rg := range rgs {
+			touches := segmentDistance(c.line, rg.entity.Position.X, rg.entity.Position.Y) <=
+				c.radius+rg.entity.CollisionRadius
+			if touches && rg.entity.CollisionLayer&c.mask != 0 && !got[rg.entity.RgId] {
+				t.Errorf("line %+v: missing Regoter(%v) at %+v", c.line, rg.entity.RgId, rg.entity.Position)
+			}
+		}
+	}
+}
+
+func TestSpatialHashQueries(t *testing.T) {
+	g, rgs := spatialTestCore(200)
+	checkSpatialQueries(t, g, rgs)
+
+	// move half of them, some into other cells, and remove a quarter
+	rnd := rand.New(rand.NewSource(2))
+	var kept []*regoterInCore
+	for i, rg := range rgs {
+		switch i % 4 {
+		case 0:
+			g.spatial.remove(rg.entity.RgId)
+			continue
+		case 1, 2:
+			rg.entity.Position.X += rnd.Float64()*2 - 1
+			rg.entity.Position.Y += rnd.Float64()*2 - 1
+			g.spatial.update(rg)
+		}
+		kept = append(kept, rg)
+	}
+	checkSpatialQueries(t, g, kept)
+	if len(g.spatial.where) != len(kept) {
+		t.Errorf("got %v regoters in the hash, want %v", len(g.spatial.where), len(kept))
+	}
+}
+
+func TestSpatialHashMaxRadius(t *testing.T) {
+	g, rgs := spatialTestCore(8)
+	if g.spatial.maxRadius != 1.5 {
+		t.Fatalf("got max radius %v, want 1.5", g.spatial.maxRadius)
+	}
+	// regoters 4 and 8 have the radius 1.5
+	g.spatial.remove(rgs[3].entity.RgId)
+	if g.spatial.maxRadius != 1.5 {
+		t.Errorf("got max radius %v with one large regoter left, want 1.5", g.spatial.maxRadius)
+	}
+	g.spatial.remove(rgs[7].entity.RgId)
+	if g.spatial.maxRadius != 0.5 {
+		t.Errorf("got max radius %v after the large regoters left, want 0.5", g.spatial.maxRadius)
+	}
+	// a regoter growing in place
+	rgs[0].entity.CollisionRadius = 1
+	g.spatial.update(rgs[0])
+	if g.spatial.maxRadius != 1 {
+		t.Errorf("got max radius %v after growing, want 1", g.spatial.maxRadius)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"image"
 	"image/color"
 	_ "image/png"
+	"lintech/rego/game/loader"
+	"log"
 	"math"
 	"sort"
 
@@ -279,3 +281,16 @@ func (s *Sprite) Pos() *geom.Vector2 {
 func (s *Sprite) PosZ() float64 {
 	return s.Entity.PosZ()
 }
+
+// loadSprite loads the sprite sheet name of the asset manifest.
+// A sprite that can not be loaded is reported and drawn as a placeholder.
+func loadSprite(name string) loader.SpriteSheet {
+	sheet, err := loader.GetSprite(name)
+	if err != nil {
+		log.Printf("Warning: Can not load sprite %q: %v", name, err)
+		img := ebiten.NewImage(64, 64)
+		img.Fill(color.RGBA{255, 0, 255, 255})
+		return loader.SpriteSheet{Img: img, Columns: 1, Rows: 1}
+	}
+	return sheet
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// A reactor recovers panics of its handler and applies its SupervisionPolicy.
+// Errors returned by ProcessMessage and recovered panics are reported to its
+// supervisor, Core for regoters, or logged when it has none.
+
+// SupervisionPolicy tells what a reactor does after its handler panicked.
+type SupervisionPolicy int
+
+const (
+	// PolicyRestart drops the message and goes on, calling Restart if the
+	// reactor implements IRestart. After maxRestarts panics within
+	// restartWindow it escalates.
+	PolicyRestart SupervisionPolicy = iota
+	// PolicyUnregister drops its messages until the supervisor removed it.
+	PolicyUnregister
+	// PolicyEscalate stops the reactor. Its supervisor removes it like with
+	// PolicyUnregister, without one it stops right away and Err tells why.
+	PolicyEscalate
+)
+
+// maxRestarts is how many panics within restartWindow a reactor with
+// PolicyRestart survives. Panics further apart are rare faults, not a loop.
+const (
+	maxRestarts   = 3
+	restartWindow = 10 * time.Second
+)
+
+// IRestart is implemented by reactors resetting their state after a panic.
+type IRestart interface {
+	Restart()
+}
+
+// Failure is an error returned by ProcessMessage or a recovered panic.
+type Failure struct {
+	Event IReactorEvent
+	Err   error
+	// stack of the panic, empty for errors
+	Stack string
+}
+
+func (f Failure) Error() string {
+	return fmt.Sprintf("handling %T: %v", f.Event, f.Err)
+}
+
+// Panicked tells if the handler panicked instead of returning an error.
+func (f Failure) Panicked() bool {
+	return f.Stack != ""
+}
+
+// Supervise reports the failures of the reactor to supervisor and applies
+// policy to its panics. Call it before Start.
+func (r *Reactor) Supervise(supervisor RcTx, policy SupervisionPolicy) {
+	r.supervisor = supervisor
+	r.policy = policy
+}
+
+// recoverPanic applies the policy of the reactor to a panic handling m.
+func (r *Reactor) recoverPanic(t IProcessMessage, m ReactorEventMessage) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	f := Failure{Event: m.event, Err: fmt.Errorf("panic: %v", v), Stack: string(debug.Stack())}
+	policy := r.policy
+	now := time.Now()
+	if policy == PolicyRestart {
+		recent := r.restarts[:0]
+		for _, at := range r.restarts {
+			if now.Sub(at) < restartWindow {
+				recent = append(recent, at)
+			}
+		}
+		r.restarts = recent
+		if len(r.restarts) >= maxRestarts {
+			policy = PolicyEscalate
+		}
+	}
+	switch policy {
+	case PolicyRestart:
+		r.restarts = append(r.restarts, now)
+		if h, ok := t.(IRestart); ok {
+			h.Restart()
+		}
+	case PolicyUnregister:
+		if r.supervisor == nil {
+			r.running = false
+		} else {
+			r.failed = true
+		}
+	case PolicyEscalate:
+		r.err = f
+		if r.supervisor == nil {
+			log.Printf("Error: %v\n%s", f, f.Stack)
+			r.running = false
+			return
+		}
+		r.failed = true
+	}
+	r.report(f, policy)
+}
+
+// Err returns the Failure the reactor stopped on with PolicyEscalate, nil
+// while it runs and when it stopped as asked.
+func (r *Reactor) Err() error {
+	select {
+	case <-r.done:
+		return r.err
+	default:
+		return nil
+	}
+}
+
+func (r *Reactor) report(f Failure, policy SupervisionPolicy) {
+	if r.supervisor == nil {
+		log.Printf("Warning: %v", f)
+		return
+	}
+	send(r.supervisor, ReactorEventMessage{r.tx, EventReactorFailed{f, policy}})
+}
+
+func (g *Core) eventHandleReactorFailed(sender RcTx, e EventReactorFailed) {
+	rg := g.findRegoterByTx(sender)
+	id := ID(NULL_ID)
+	if rg != nil {
+		id = rg.entity.RgId
+	}
+	if !e.Failure.Panicked() {
+		log.Printf("Warning: Regoter(%v) failed %v", id, e.Failure)
+		return
+	}
+	log.Printf("Warning: Regoter(%v) failed %v\n%s", id, e.Failure, e.Failure.Stack)
+	if e.Policy == PolicyRestart {
+		return
+	}
+	if rg == nil {
+		send(sender, ReactorEventMessage{g.tx, EventUnregisterConfirmed{}})
+		return
+	}
+	g.removeRegoter(g.rgs[rg.rgType], rg)
+	g.cleared[id] = true
+}
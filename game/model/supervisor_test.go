@@ -0,0 +1,120 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harbdog/raycaster-go"
+)
+
+// faulty panics on every tick and fails on every draw.
+type faulty struct {
+	Reactor
+	restarted int
+}
+
+func (f *faulty) Restart() {
+	f.restarted++
+}
+
+func (f *faulty) ProcessMessage(m ReactorEventMessage) error {
+	switch m.event.(type) {
+	case EventUpdateTick:
+		panic("bad tick")
+	case EventDraw:
+		return errors.New("bad draw")
+	}
+	return nil
+}
+
+func TestPanickingRegoterIsUnregistered(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	enemyTx := NewEnemy(coreTx, Position{X: start.X, Y: start.Y}, DrawInfo{}, 1,
+		CollisionSpace{}, 0, 0, raycaster.AnchorBottom, nil)
+	f := &faulty{Reactor: NewReactor()}
+	f.Supervise(coreTx, PolicyUnregister)
+	f.Reactor.Start(f)
+	entity := Entity{RgId: <-IdGen, RgType: RegoterEnumSprite, Position: Position{X: start.X + 0.5, Y: start.Y}}
+	coreTx <- ReactorEventMessage{f.tx, EventRegisterRegoter{f.tx, RegoterData{Entity: entity}, f.done}}
+	coreTx <- ReactorEventMessage{nil, EventGameTick{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	select {
+	case <-f.Done():
+	case <-ctx.Done():
+		t.Fatal("the panicking regoter was not removed")
+	}
+	if _, err := Ask[EventHealth](ctx, enemyTx, EventAskHealth{}); err != nil {
+		t.Errorf("the enemy next to it stopped: %v", err)
+	}
+}
+
+func TestRestartPolicyEscalates(t *testing.T) {
+	supervisor := make(chan ReactorEventMessage, 10)
+	f := &faulty{Reactor: NewReactor()}
+	f.Supervise(supervisor, PolicyRestart)
+
+	f.handle(f, ReactorEventMessage{nil, EventDraw{}})
+	if e := expectEvent[EventReactorFailed](t, supervisor); e.Failure.Panicked() {
+		t.Errorf("got a panic for an error: %v", e.Failure)
+	}
+	restart := func() {
+		t.Helper()
+		f.handle(f, ReactorEventMessage{nil, EventUpdateTick{}})
+		if e := expectEvent[EventReactorFailed](t, supervisor); !e.Failure.Panicked() || e.Policy != PolicyRestart {
+			t.Fatalf("got %v with policy %v, want a panic and a restart", e.Failure, e.Policy)
+		}
+	}
+	for i := 0; i < maxRestarts; i++ {
+		restart()
+	}
+	if f.restarted != maxRestarts || !f.running {
+		t.Fatalf("got %v restarts, running %v, want %v restarts and running", f.restarted, f.running, maxRestarts)
+	}
+
+	// panics further apart than restartWindow do not add up
+	for i := range f.Reactor.restarts {
+		f.Reactor.restarts[i] = f.Reactor.restarts[i].Add(-restartWindow)
+	}
+	for i := 0; i < maxRestarts; i++ {
+		restart()
+	}
+	if f.restarted != 2*maxRestarts || !f.running {
+		t.Fatalf("got %v restarts, running %v, want %v restarts and running", f.restarted, f.running, 2*maxRestarts)
+	}
+
+	f.handle(f, ReactorEventMessage{nil, EventUpdateTick{}})
+	if e := expectEvent[EventReactorFailed](t, supervisor); e.Policy != PolicyEscalate {
+		t.Fatalf("got policy %v after too many restarts, want PolicyEscalate", e.Policy)
+	}
+	f.handle(f, ReactorEventMessage{nil, EventUnregisterConfirmed{}})
+	if f.running {
+		t.Error("the escalated reactor still runs after its removal")
+	}
+}
+
+func TestEscalatedWorldIsDone(t *testing.T) {
+	w := NewWorld(GameCfg{})
+	f := &faulty{Reactor: w.NewReactor()}
+	// like Core, the reactor has no supervisor
+	f.Supervise(nil, PolicyEscalate)
+	w.core = &f.Reactor
+	f.Reactor.Start(f)
+	if w.Err() != nil {
+		t.Fatalf("got %v before the panic", w.Err())
+	}
+	f.tx <- ReactorEventMessage{nil, EventUpdateTick{}}
+
+	select {
+	case <-w.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the escalated reactor did not stop")
+	}
+	var failure Failure
+	if !errors.As(w.Err(), &failure) || !failure.Panicked() {
+		t.Errorf("got %v, want the panic of the tick", w.Err())
+	}
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"math"
+
+	"github.com/harbdog/raycaster-go/geom3d"
+)
+
+// Moving entities are swept along their whole move, so fast ones can not pass
+// through thin sprites between two ticks. Projectiles collide as spheres of
+// their collision radius, everything else as upright cylinders of their
+// collision radius and height.
+
+// iterations of the searches for the hit of a sphere, enough for float precision
+const sweepIterations = 64
+
+func isCollisionSphere(e *Entity) bool {
+	return e.RgType == RegoterEnumProjectile
+}
+
+// collisionExtent returns how far the collision shape of e reaches below and above its position.
+func collisionExtent(e *Entity) (float64, float64) {
+	if isCollisionSphere(e) {
+		return e.CollisionRadius, e.CollisionRadius
+	}
+	minZ, maxZ := zEntityMinMax(0, e)
+	return -minZ, maxZ
+}
+
+// sweptHit returns the line param (0 to 1) where mover moving from start to
+// end first touches target, the point on target it touches and the normal of
+// the surface of target there.
+// A mover already touching target only hits it when moving toward it, so the
+// two can get apart again.
+func sweptHit(mover *Entity, start, end Position, target *Entity) (float64, Position, geom3d.Vector3, bool) {
+	below, above := collisionExtent(mover)
+	tgtMinZ, tgtMaxZ := zEntityMinMax(target.Position.Z, target)
+	d := Position{X: end.X - start.X, Y: end.Y - start.Y, Z: end.Z - start.Z}
+
+	// the bounding cylinder of the mover, exact for cylinders
+	rx, ry := start.X-target.Position.X, start.Y-target.Position.Y
+	r0, r1, ok := circleSpan(rx, ry, d.X, d.Y, mover.CollisionRadius+target.CollisionRadius)
+	if !ok {
+		return 0, Position{}, geom3d.Vector3{}, false
+	}
+	z0, z1, ok := axisSpan(start.Z, d.Z, tgtMinZ-above, tgtMaxZ+below)
+	if !ok {
+		return 0, Position{}, geom3d.Vector3{}, false
+	}
+	enter, exit := math.Max(r0, z0), math.Min(r1, z1)
+	if enter > exit || exit < 0 || enter > 1 {
+		return 0, Position{}, geom3d.Vector3{}, false
+	}
+
+	t := math.Max(enter, 0)
+	// the cylinders touch on the side unless they first overlap in height
+	side := r0 >= z0
+	if isCollisionSphere(mover) {
+		// the sphere is inside its bounding cylinder, so it can not hit earlier
+		if t, ok = sweptSphereHit(start, d, mover.CollisionRadius, target, t); !ok {
+			return 0, Position{}, geom3d.Vector3{}, false
+		}
+	}
+	if t == 0 {
+		// already touching, center distance has to shrink
+		rz := start.Z + (above-below)/2 - (tgtMinZ+tgtMaxZ)/2
+		if rx*d.X+ry*d.Y+rz*d.Z >= 0 {
+			return 0, Position{}, geom3d.Vector3{}, false
+		}
+		side = true
+	}
+
+	at := Position{X: start.X + t*d.X, Y: start.Y + t*d.Y, Z: start.Z + t*d.Z}
+	point := contactPoint(at, below, above, target)
+
+	var normal geom3d.Vector3
+	switch {
+	case isCollisionSphere(mover):
+		// from the closest point toward the center of the sphere
+		normal = geom3d.Vector3{X: at.X - point.X, Y: at.Y - point.Y, Z: at.Z - point.Z}
+	case side:
+		normal = geom3d.Vector3{X: at.X - target.Position.X, Y: at.Y - target.Position.Y}
+	default:
+		normal = geom3d.Vector3{Z: -math.Copysign(1, d.Z)}
+	}
+	if length := math.Sqrt(normal.X*normal.X + normal.Y*normal.Y + normal.Z*normal.Z); length > 0 {
+		normal = geom3d.Vector3{X: normal.X / length, Y: normal.Y / length, Z: normal.Z / length}
+	}
+	return t, point, normal, true
+}
+
+// sweptSphereHit returns the first line param from t0 on where the sphere
+// moving from start along d touches the cylinder of target.
+// The distance of a point moving on a line to a convex shape is convex, so its
+// minimum is found with a golden section search and the first touch by
+// bisection before it.
+func sweptSphereHit(start, d Position, radius float64, target *Entity, t0 float64) (float64, bool) {
+	gap := func(t float64) float64 {
+		p := Position{X: start.X + t*d.X, Y: start.Y + t*d.Y, Z: start.Z + t*d.Z}
+		return cylinderDistance(p, target) - radius
+	}
+	if gap(t0) <= 0 {
+		return t0, true
+	}
+
+	invPhi := (math.Sqrt(5) - 1) / 2
+	lo, hi := t0, 1.0
+	a, b := hi-invPhi*(hi-lo), lo+invPhi*(hi-lo)
+	ga, gb := gap(a), gap(b)
+	for i := 0; i < sweepIterations && ga > 0 && gb > 0; i++ {
+		if ga < gb {
+			hi, b, gb = b, a, ga
+			a = hi - invPhi*(hi-lo)
+			ga = gap(a)
+		} else {
+			lo, a, ga = a, b, gb
+			b = lo + invPhi*(hi-lo)
+			gb = gap(b)
+		}
+	}
+	tMin := a
+	if gb < ga {
+		tMin = b
+	}
+	if math.Min(ga, gb) > 0 {
+		if gap(1) > 0 {
+			return 0, false
+		}
+		tMin = 1
+	}
+
+	lo, hi = t0, tMin
+	for i := 0; i < sweepIterations; i++ {
+		mid := (lo + hi) / 2
+		if gap(mid) > 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, true
+}
+
+// cylinderDistance returns the distance of p to the collision cylinder of e.
+func cylinderDistance(p Position, e *Entity) float64 {
+	minZ, maxZ := zEntityMinMax(e.Position.Z, e)
+	h := math.Max(math.Hypot(p.X-e.Position.X, p.Y-e.Position.Y)-e.CollisionRadius, 0)
+	v := math.Max(math.Max(minZ-p.Z, p.Z-maxZ), 0)
+	return math.Hypot(h, v)
+}
+
+// contactPoint returns the point of the cylinder of target closest to a
+// mover at position at, reaching from below to above it.
+func contactPoint(at Position, below, above float64, target *Entity) Position {
+	tp := target.Position
+	point := Position{X: at.X, Y: at.Y}
+	dx, dy := at.X-tp.X, at.Y-tp.Y
+	if dist := math.Hypot(dx, dy); dist > target.CollisionRadius {
+		scale := target.CollisionRadius / dist
+		point.X, point.Y = tp.X+dx*scale, tp.Y+dy*scale
+	}
+	minZ, maxZ := zEntityMinMax(tp.Z, target)
+	point.Z = math.Max(math.Max(minZ, at.Z-below), math.Min(math.Min(maxZ, at.Z+above), at.Z))
+	return point
+}
+
+// circleSpan returns the line params where the point (x, y) moving along
+// (dx, dy) is within radius of the origin.
+func circleSpan(x, y, dx, dy, radius float64) (float64, float64, bool) {
+	a := dx*dx + dy*dy
+	b := 2 * (x*dx + y*dy)
+	c := x*x + y*y - radius*radius
+	if a == 0 {
+		if c > 0 {
+			return 0, 0, false
+		}
+		return math.Inf(-1), math.Inf(1), true
+	}
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return 0, 0, false
+	}
+	sq := math.Sqrt(disc)
+	return (-b - sq) / (2 * a), (-b + sq) / (2 * a), true
+}
+
+// axisSpan returns the line params where pos moving along d is between min and max on one axis.
+func axisSpan(pos, d, min, max float64) (float64, float64, bool) {
+	if d == 0 {
+		if pos < min || pos > max {
+			return 0, 0, false
+		}
+		return math.Inf(-1), math.Inf(1), true
+	}
+	t0, t1 := (min-pos)/d, (max-pos)/d
+	if t0 > t1 {
+		t0, t1 = t1, t0
+	}
+	return t0, t1, true
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"log"
+)
+
+// Core reports the ticks skipped by regoters every tickReportInterval ticks, about once a second.
+const tickReportInterval = 60
+
+// sendTick sends a tick to rg without waiting. While there is room the tick
+// goes in order with the other messages. A full mailbox keeps it aside, where
+// a newer tick replaces it and it is counted as skipped, so a slow regoter
+// falls behind alone instead of stalling Core and every frame with it.
+func (g *Core) sendTick(rg *regoterInCore, m ReactorEventMessage) {
+	if rg.mailbox == nil {
+		send(rg.tx, m)
+		return
+	}
+	if rg.mailbox.replace(m) {
+		rg.skippedTicks++
+		g.skippedTicks++
+	} else {
+		select {
+		case rg.tx <- m:
+		default:
+			rg.mailbox.coalesce(m)
+		}
+	}
+	rg.mailbox.notify()
+}
+
+func (g *Core) reportSkippedTicks() {
+	if g.ticks%tickReportInterval != 0 || g.skippedTicks == 0 {
+		return
+	}
+	log.Printf("Warning: Regoters skipped %v ticks in the last %v ticks.", g.skippedTicks, tickReportInterval)
+	g.skippedTicks = 0
+}
+
+func (g *Core) eventHandleAskTickStats(sender RcTx, e EventAskTickStats) {
+	stats := EventTickStats{Ticks: g.ticks, SkippedBy: map[ID]int{}}
+	for _, l := range g.rgs {
+		for id, rg := range l {
+			if rg.skippedTicks > 0 {
+				stats.Skipped += rg.skippedTicks
+				stats.SkippedBy[id] = rg.skippedTicks
+			}
+		}
+	}
+	send(sender, ReactorEventMessage{g.tx, stats})
+}
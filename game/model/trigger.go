@@ -0,0 +1,175 @@
+package model
+
+import (
+	"math"
+	"sort"
+
+	"github.com/harbdog/raycaster-go/geom"
+)
+
+// Trigger is a region that is not solid. Core tells its owner and the entities
+// on the layers of Mask when they enter or leave it.
+type Trigger struct {
+	Id    ID
+	Shape TriggerShape
+	Mask  CollisionLayer
+}
+
+func NewTrigger(shape TriggerShape, mask CollisionLayer) Trigger {
+	return Trigger{Id: <-IdGen, Shape: shape, Mask: mask}
+}
+
+// TriggerShape is the region of a trigger in the X-Y plane.
+type TriggerShape interface {
+	contains(x, y float64) bool
+	// crossedBy reports whether line passes through the shape
+	crossedBy(line geom.Line) bool
+}
+
+type TriggerCircle struct {
+	X, Y, Radius float64
+}
+
+func (c TriggerCircle) contains(x, y float64) bool {
+	return geom.Distance2(x, y, c.X, c.Y) <= c.Radius*c.Radius
+}
+
+func (c TriggerCircle) crossedBy(line geom.Line) bool {
+	return c.contains(line.X1, line.Y1) ||
+		len(geom.LineCircleIntersection(line, geom.Circle{X: c.X, Y: c.Y, Radius: c.Radius}, true)) > 0
+}
+
+type TriggerBox struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+func (b TriggerBox) contains(x, y float64) bool {
+	return x >= b.MinX && x <= b.MaxX && y >= b.MinY && y <= b.MaxY
+}
+
+func (b TriggerBox) crossedBy(line geom.Line) bool {
+	x0, x1, ok := axisSpan(line.X1, line.X2-line.X1, b.MinX, b.MaxX)
+	if !ok {
+		return false
+	}
+	y0, y1, ok := axisSpan(line.Y1, line.Y2-line.Y1, b.MinY, b.MaxY)
+	if !ok {
+		return false
+	}
+	enter, exit := math.Max(x0, y0), math.Min(x1, y1)
+	return enter <= exit && exit >= 0 && enter <= 1
+}
+
+// TriggerCells is a set of map grid cells.
+type TriggerCells map[[2]int]bool
+
+func NewTriggerCells(cells ...[2]int) TriggerCells {
+	t := TriggerCells{}
+	for _, c := range cells {
+		t[c] = true
+	}
+	return t
+}
+
+func (t TriggerCells) contains(x, y float64) bool {
+	return t[[2]int{int(math.Floor(x)), int(math.Floor(y))}]
+}
+
+func (t TriggerCells) crossedBy(line geom.Line) bool {
+	for c := range t {
+		box := TriggerBox{float64(c[0]), float64(c[1]), float64(c[0] + 1), float64(c[1] + 1)}
+		if box.crossedBy(line) {
+			return true
+		}
+	}
+	return false
+}
+
+type triggerInCore struct {
+	trigger Trigger
+	owner   RcTx
+	// entities inside the trigger
+	inside map[ID]bool
+}
+
+func (g *Core) eventHandleRegisterTrigger(sender RcTx, e EventRegisterTrigger) {
+	tr := &triggerInCore{trigger: e.Trigger, owner: sender, inside: map[ID]bool{}}
+	g.triggers = append(g.triggers, tr)
+	// the entities already inside enter it now, in the order of their IDs
+	var entered []*regoterInCore
+	for t, l := range g.rgs {
+		if !spatialTypes[t] {
+			continue
+		}
+		for _, rg := range l {
+			p := rg.entity.Position
+			if rg.entity.collisionLayer()&tr.trigger.Mask != 0 && tr.trigger.Shape.contains(p.X, p.Y) {
+				entered = append(entered, rg)
+			}
+		}
+	}
+	sort.Slice(entered, func(i, j int) bool { return entered[i].entity.RgId < entered[j].entity.RgId })
+	for _, rg := range entered {
+		tr.inside[rg.entity.RgId] = true
+		g.sendTriggerEvent(tr, rg, EventTriggerEnter{TriggerId: tr.trigger.Id, RgId: rg.entity.RgId})
+	}
+}
+
+func (g *Core) eventHandleUnregisterTrigger(sender RcTx, e EventUnregisterTrigger) {
+	g.removeTriggers(func(tr *triggerInCore) bool { return tr.trigger.Id == e.TriggerId })
+}
+
+// removeTriggers removes the triggers remove reports true for, keeping the order of the others.
+func (g *Core) removeTriggers(remove func(tr *triggerInCore) bool) {
+	kept := g.triggers[:0]
+	for _, tr := range g.triggers {
+		if !remove(tr) {
+			kept = append(kept, tr)
+		}
+	}
+	g.triggers = kept
+}
+
+// updateTriggers sends EventTriggerEnter and EventTriggerExit for the move of p
+// from start to its position. A move passing through a trigger enters and leaves it.
+// With start at its position, as for a regoter that registers or is restored,
+// it enters the triggers it is inside.
+func (g *Core) updateTriggers(p *regoterInCore, start Position) {
+	pe := &p.entity
+	line := geom.Line{X1: start.X, Y1: start.Y, X2: pe.Position.X, Y2: pe.Position.Y}
+	for _, tr := range g.triggers {
+		if pe.collisionLayer()&tr.trigger.Mask == 0 {
+			continue
+		}
+		enter := EventTriggerEnter{TriggerId: tr.trigger.Id, RgId: pe.RgId}
+		exit := EventTriggerExit{TriggerId: tr.trigger.Id, RgId: pe.RgId}
+		was, is := tr.inside[pe.RgId], tr.trigger.Shape.contains(pe.Position.X, pe.Position.Y)
+		switch {
+		case !was && is:
+			tr.inside[pe.RgId] = true
+			g.sendTriggerEvent(tr, p, enter)
+		case was && !is:
+			delete(tr.inside, pe.RgId)
+			g.sendTriggerEvent(tr, p, exit)
+		case !was && !is && tr.trigger.Shape.crossedBy(line):
+			g.sendTriggerEvent(tr, p, enter)
+			g.sendTriggerEvent(tr, p, exit)
+		}
+	}
+}
+
+func (g *Core) sendTriggerEvent(tr *triggerInCore, p *regoterInCore, e IReactorEvent) {
+	m := ReactorEventMessage{g.tx, e}
+	send(tr.owner, m)
+	if p.tx != tr.owner {
+		send(p.tx, m)
+	}
+}
+
+// removeFromTriggers forgets the regoter and the triggers it owns.
+func (g *Core) removeFromTriggers(rg *regoterInCore) {
+	g.removeTriggers(func(tr *triggerInCore) bool { return tr.owner == rg.tx })
+	for _, tr := range g.triggers {
+		delete(tr.inside, rg.entity.RgId)
+	}
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// triggerEvents returns the trigger events that arrived on rx once Core handled
+// everything sent to it so far.
+func triggerEvents(t *testing.T, coreTx RcTx, rx chan ReactorEventMessage) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := Ask[EventTickStats](ctx, coreTx, EventAskTickStats{}); err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for {
+		select {
+		case m := <-rx:
+			switch e := m.event.(type) {
+			case EventTriggerEnter:
+				events = append(events, fmt.Sprintf("enter %v", e.RgId))
+			case EventTriggerExit:
+				events = append(events, fmt.Sprintf("exit %v", e.RgId))
+			}
+		default:
+			return events
+		}
+	}
+}
+
+// triggerTestEntity is an enemy at (x, y), each move takes it velocity further along X.
+func triggerTestEntity(x, y, velocity float64) Entity {
+	return Entity{
+		RgId:           <-IdGen,
+		RgType:         RegoterEnumSprite,
+		Position:       Position{X: x, Y: y},
+		Velocity:       velocity,
+		CollisionLayer: CollisionLayerEnemy,
+	}
+}
+
+func TestTriggerShapes(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	y := start.Y
+	for _, c := range []struct {
+		name     string
+		shape    TriggerShape
+		velocity float64
+		moves    int
+	}{
+		// MaximumVelocity steps into and out of the box
+		{"box", TriggerBox{MinX: start.X + 0.25, MinY: y - 0.5, MaxX: start.X + 0.45, MaxY: y + 0.5}, MaximumVelocity, 6},
+		{"circle", TriggerCircle{X: start.X + 0.35, Y: y, Radius: 0.1}, MaximumVelocity, 6},
+		// one move of two cells passes through the cell in between
+		{"cells", NewTriggerCells([2]int{int(start.X) + 1, int(y)}), 2, 1},
+	} {
+		owner := make(chan ReactorEventMessage, 100)
+		trigger := NewTrigger(c.shape, CollisionLayerEnemy)
+		coreTx <- ReactorEventMessage{owner, EventRegisterTrigger{trigger}}
+		entity := triggerTestEntity(start.X, y, c.velocity)
+		rx := registerTestRegoter(t, coreTx, entity)
+		for i := 0; i < c.moves; i++ {
+			coreTx <- ReactorEventMessage{rx, EventMovement{RgId: entity.RgId}}
+		}
+
+		want := []string{fmt.Sprintf("enter %v", entity.RgId), fmt.Sprintf("exit %v", entity.RgId)}
+		if got := triggerEvents(t, coreTx, owner); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: owner got %v, want %v", c.name, got, want)
+		}
+		if got := triggerEvents(t, coreTx, rx); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: entity got %v, want %v", c.name, got, want)
+		}
+		coreTx <- ReactorEventMessage{rx, EventUnregisterRegoter{RgId: entity.RgId}}
+		coreTx <- ReactorEventMessage{owner, EventUnregisterTrigger{TriggerId: trigger.Id}}
+	}
+}
+
+func TestTriggerMask(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	owner := make(chan ReactorEventMessage, 100)
+	coreTx <- ReactorEventMessage{owner, EventRegisterTrigger{
+		NewTrigger(TriggerCircle{X: start.X, Y: start.Y, Radius: 1}, CollisionLayerPlayer)}}
+	registerTestRegoter(t, coreTx, triggerTestEntity(start.X, start.Y, 0))
+	if got := triggerEvents(t, coreTx, owner); len(got) != 0 {
+		t.Errorf("owner got %v for an enemy, want nothing", got)
+	}
+
+	// an entity without a layer is on all of them
+	entity := triggerTestEntity(start.X, start.Y, 0)
+	entity.CollisionLayer = 0
+	registerTestRegoter(t, coreTx, entity)
+	want := []string{fmt.Sprintf("enter %v", entity.RgId)}
+	if got := triggerEvents(t, coreTx, owner); !reflect.DeepEqual(got, want) {
+		t.Errorf("owner got %v for an entity without a layer, want %v", got, want)
+	}
+}
+
+func TestTriggerEnteredWithoutMoving(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	circle := TriggerCircle{X: start.X, Y: start.Y, Radius: 0.5}
+	before := triggerTestEntity(start.X, start.Y, 0)
+	beforeRx := registerTestRegoter(t, coreTx, before)
+
+	// the trigger comes to the entity
+	owner := make(chan ReactorEventMessage, 100)
+	coreTx <- ReactorEventMessage{owner, EventRegisterTrigger{NewTrigger(circle, CollisionLayerEnemy)}}
+	// the entity registers inside the trigger
+	inside := triggerTestEntity(start.X+0.1, start.Y, 0)
+	insideRx := registerTestRegoter(t, coreTx, inside)
+	// the entity is restored into the trigger
+	restored := triggerTestEntity(start.X+2, start.Y, 0)
+	restoredRx := registerTestRegoter(t, coreTx, restored)
+	saved := restored
+	saved.Position.X = start.X - 0.1
+	coreTx <- ReactorEventMessage{nil, EventRestoreRegoter{Tx: restoredRx, Saved: RegoterSnapshot{Entity: saved}}}
+
+	want := []string{
+		fmt.Sprintf("enter %v", before.RgId),
+		fmt.Sprintf("enter %v", inside.RgId),
+		fmt.Sprintf("enter %v", restored.RgId),
+	}
+	if got := triggerEvents(t, coreTx, owner); !reflect.DeepEqual(got, want) {
+		t.Errorf("owner got %v, want %v", got, want)
+	}
+	for i, rx := range []chan ReactorEventMessage{beforeRx, insideRx, restoredRx} {
+		if got := triggerEvents(t, coreTx, rx); !reflect.DeepEqual(got, want[i:i+1]) {
+			t.Errorf("entity got %v, want %v", got, want[i:i+1])
+		}
+	}
+}
+
+func TestTriggerOwnerMoving(t *testing.T) {
+	coreTx, start := newHeadlessTestCore(t)
+	entity := triggerTestEntity(start.X, start.Y, MaximumVelocity)
+	rx := registerTestRegoter(t, coreTx, entity)
+	// the entity owns the trigger it walks into
+	shape := TriggerBox{MinX: start.X + 0.05, MinY: start.Y - 0.5, MaxX: start.X + 1, MaxY: start.Y + 0.5}
+	coreTx <- ReactorEventMessage{rx, EventRegisterTrigger{NewTrigger(shape, CollisionLayerEnemy)}}
+	coreTx <- ReactorEventMessage{rx, EventMovement{RgId: entity.RgId}}
+
+	want := []string{fmt.Sprintf("enter %v", entity.RgId)}
+	if got := triggerEvents(t, coreTx, rx); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want a single %v", got, want)
+	}
+}
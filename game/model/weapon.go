@@ -2,8 +2,6 @@ package model
 
 import (
 	"image/color"
-	"lintech/rego/game/loader"
-	"log"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/harbdog/raycaster-go"
@@ -20,10 +18,9 @@ type WeaponTemplate struct {
 type Weapon struct {
 	Reactor
 	WeaponTemplate
-	coreTx       RcTx
-	firing       bool
-	fireWeapon   ICooldownFlag
-	unregistered bool
+	coreTx     RcTx
+	firing     bool
+	fireWeapon ICooldownFlag
 	// fireWeapon bool
 }
 
@@ -37,46 +34,24 @@ var (
 	yellow  = color.RGBA{255, 200, 0, 196}
 )
 
-func (r *Weapon) ProcessMessage(m ReactorEventMessage) error {
-	// log.Print(fmt.Sprintf("(%v) recv %T", r.thing.GetData().Entity.RgId, e))
-	switch m.event.(type) {
-	case EventUnregisterConfirmed:
-		r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-	default:
-		if !r.unregistered {
-			switch m.event.(type) {
-			case EventUpdateTick:
-				r.eventHandleUpdateTick(m.sender, m.event.(EventUpdateTick))
-			case EventUnregisterConfirmed:
-				r.eventHandleUnregisterConfirmed(m.sender, m.event.(EventUnregisterConfirmed))
-			case EventCfgChanged:
-				r.eventHandleCfgChanged(m.sender, m.event.(EventCfgChanged))
-			case EventFireWeapon:
-				r.eventHandleFireWeapon(m.sender, m.event.(EventFireWeapon))
-			case EventHolsterWeapon:
-				r.eventHandleHolsterWeapon(m.sender, m.event.(EventHolsterWeapon))
-			default:
-				r.eventHandleUnknown(m.sender, m.event)
-			}
-		}
-	}
-	return nil
+// registerHandlers declares the events the Weapon handles.
+func (r *Weapon) registerHandlers() {
+	On(&r.Reactor, r.eventHandleUpdateTick)
+	On(&r.Reactor, r.eventHandleCfgChanged)
+	On(&r.Reactor, r.eventHandleFireWeapon)
+	On(&r.Reactor, r.eventHandleHolsterWeapon)
 }
 
 // Update the position and status of Regoter
 // And send new Position and status to IGame
-func (r *Weapon) eventHandleUnregisterConfirmed(sender RcTx, e EventUnregisterConfirmed) {
-	r.running = false
-}
-
 func (w *Weapon) eventHandleHolsterWeapon(sender RcTx, e EventHolsterWeapon) {
 	m := ReactorEventMessage{w.tx, EventUnregisterRegoter{RgId: w.rgData.Entity.RgId}}
 	// Not send to sender. Sender is Player. We need send to Core.
-	w.coreTx <- m
-	w.unregistered = true
+	send(w.coreTx, m)
+	w.Unregistering()
 }
 
-func (w *Weapon) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) error {
+func (w *Weapon) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) {
 	w.fireWeapon.cooldown()
 	if w.fireWeapon.get() {
 		w.projectile.Spawn(sender, w.WeaponTemplate.projectile, e.PlayerEntity.RgId,
@@ -86,17 +61,16 @@ func (w *Weapon) eventHandleUpdateTick(sender RcTx, e EventUpdateTick) error {
 			startAnimation := ReactorEventMessage{w.tx, EventMovement{
 				RgId:    w.rgData.Entity.RgId,
 				Command: Command{StartAnimation: true}}}
-			sender <- startAnimation
+			send(sender, startAnimation)
 		}
 	} else {
 		if e.RgState.AnimationRunning && e.RgState.AnimationLoopCnt >= 1 {
 			stopAnimation := ReactorEventMessage{w.tx, EventMovement{
 				RgId:    w.rgData.Entity.RgId,
 				Command: Command{StopAnimation: true}}}
-			sender <- stopAnimation
+			send(sender, stopAnimation)
 		}
 	}
-	return nil
 }
 
 func (w *Weapon) playAudio(e EventUpdateTick) {
@@ -110,14 +84,8 @@ func (w *Weapon) eventHandleCfgChanged(sender RcTx, e EventCfgChanged) {
 	w.coreTx = sender
 	w.cfg = e.Cfg
 }
-func (w *Weapon) eventHandleFireWeapon(sender RcTx, e EventFireWeapon) error {
+func (w *Weapon) eventHandleFireWeapon(sender RcTx, e EventFireWeapon) {
 	w.fireWeapon.set()
-	return nil
-}
-
-func (r *Weapon) eventHandleUnknown(sender RcTx, e IReactorEvent) error {
-	log.Fatalf("Unknown event: %T", e)
-	return nil
 }
 
 func NewWeaponChargedBolt(coreTx RcTx) *WeaponTemplate {
@@ -126,10 +94,12 @@ func NewWeaponChargedBolt(coreTx RcTx) *WeaponTemplate {
 
 	RoF := 2.0
 	scale := 1.0
+	sheet := loadSprite("hand_spell")
 	di := DrawInfo{
-		Img:           loader.GetSpriteFromFile("hand_spell.png"),
-		Columns:       3,
-		Rows:          1,
+		Asset:         "hand_spell",
+		Img:           sheet.Img,
+		Columns:       sheet.Columns,
+		Rows:          sheet.Rows,
 		AnimationRate: 7,
 	}
 	audioPlayer := LoadAudioPlayer("blaster.mp3")
@@ -143,10 +113,12 @@ func NewWeaponRedBolt(coreTx RcTx) *WeaponTemplate {
 
 	RoF := 6.0
 	scale := 1.0
+	sheet := loadSprite("hand_staff")
 	di := DrawInfo{
-		Img:           loader.GetSpriteFromFile("hand_staff.png"),
-		Columns:       3,
-		Rows:          1,
+		Asset:         "hand_staff",
+		Img:           sheet.Img,
+		Columns:       sheet.Columns,
+		Rows:          sheet.Rows,
 		AnimationRate: 7,
 	}
 	audioPlayer := LoadAudioPlayer("jab.wav")
@@ -192,15 +164,17 @@ func NewWeaponTemplate(coreTx RcTx, di DrawInfo, scale float64,
 func NewWeapon(coreTx RcTx, tp *WeaponTemplate) RcTx {
 	cooldownInit := int(float64(ebiten.TPS())/float64(tp.rateOfFire)) + 1
 	w := &Weapon{
-		Reactor:        NewReactor(),
+		Reactor:        WorldOf(coreTx).NewReactor(),
 		WeaponTemplate: *tp,
 		fireWeapon:     &cooldownFlag{counterInit: cooldownInit},
 	}
 	// Don't use ID of Template
 	w.rgData.Entity.RgId = <-IdGen
-	go w.Reactor.Run(w)
-	m := ReactorEventMessage{w.tx, EventRegisterRegoter{w.tx, w.rgData}}
-	coreTx <- m
+	w.Supervise(coreTx, PolicyRestart)
+	w.registerHandlers()
+	w.Reactor.Start(w)
+	m := ReactorEventMessage{w.tx, EventRegisterRegoter{w.tx, w.rgData, w.done}}
+	send(coreTx, m)
 
 	return w.tx
 }
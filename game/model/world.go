@@ -0,0 +1,128 @@
+package model
+
+import (
+	"math/rand"
+)
+
+// World is how the reactors of one game run, on goroutines of their own, on a
+// WorkerPool or on the deterministic Scheduler, and how many messages their
+// mailboxes hold. Core makes it from its GameCfg
+// and the reactors made with NewReactor of the World run in it, so games in
+// one process do not change each other.
+type World struct {
+	// nil when every reactor runs on its own goroutine
+	scheduler *Scheduler
+	// runs the regoters, Core keeps a goroutine of its own
+	workers *WorkerPool
+	// mailbox capacities of the regoters and Core
+	regoterCapacity, coreCapacity int
+	// the Core of the world, nil until it is made
+	core *Reactor
+}
+
+// NewWorld returns the World for cfg.
+func NewWorld(cfg GameCfg) *World {
+	w := &World{regoterCapacity: defaultRegoterMailboxCapacity, coreCapacity: defaultCoreMailboxCapacity}
+	if cfg.MailboxCapacity > 0 {
+		w.regoterCapacity = cfg.MailboxCapacity
+	}
+	if cfg.CoreMailboxCapacity > 0 {
+		w.coreCapacity = cfg.CoreMailboxCapacity
+	}
+	if cfg.Deterministic {
+		w.scheduler = &Scheduler{rand: rand.New(rand.NewSource(cfg.Seed))}
+	} else if cfg.Workers > 0 {
+		w.workers = NewWorkerPool(cfg.Workers)
+	}
+	return w
+}
+
+// Close ends the WorkerPool of w, if it has one, once the reactors on it stopped.
+func (w *World) Close() {
+	if p := w.pool(); p != nil {
+		p.Close()
+	}
+}
+
+// WorldOf returns the World of the reactor of tx, like the Core regoters are
+// created for. It is nil for channels of no running reactor and of reactors
+// made outside of a World.
+func WorldOf(tx RcTx) *World {
+	mb := findMailbox(tx)
+	if mb == nil {
+		return nil
+	}
+	return mb.world
+}
+
+// NewReactor returns a regoter reactor of w with a mailbox of the capacity of w.
+// A nil World makes one of the default capacity running on its own goroutine.
+func (w *World) NewReactor() Reactor {
+	if w == nil {
+		return NewReactor()
+	}
+	r := NewReactorWithCapacity(w.regoterCapacity)
+	r.world = w
+	return r
+}
+
+func (w *World) newReactorCore() Reactor {
+	r := NewReactorWithCapacity(w.coreCapacity)
+	r.world = w
+	return r
+}
+
+// Done returns a channel closed when the Core of w stopped, nil if w has no Core.
+func (w *World) Done() <-chan struct{} {
+	if w == nil || w.core == nil {
+		return nil
+	}
+	return w.core.Done()
+}
+
+// Err returns why the Core of w stopped, nil while it runs and after Shutdown.
+func (w *World) Err() error {
+	if w == nil || w.core == nil {
+		return nil
+	}
+	return w.core.Err()
+}
+
+// Scheduler returns the deterministic Scheduler of w, nil if it has none.
+func (w *World) Scheduler() *Scheduler {
+	if w == nil {
+		return nil
+	}
+	return w.scheduler
+}
+
+func (w *World) pool() *WorkerPool {
+	if w == nil {
+		return nil
+	}
+	return w.workers
+}
+
+// Settle delivers the messages of the reactors of w until all are idle if w
+// runs deterministically, else the reactors handle them on their own.
+func (w *World) Settle() {
+	if s := w.Scheduler(); s != nil {
+		s.Settle()
+	}
+}
+
+// randFloat64 returns a random number in [0.0, 1.0), from the seeded source in deterministic mode.
+func (w *World) randFloat64() float64 {
+	if s := w.Scheduler(); s != nil {
+		return s.rand.Float64()
+	}
+	return rand.Float64()
+}
+
+// randIntn returns a random number in [0, n), from the seeded source in deterministic mode.
+func (w *World) randIntn(n int) int {
+	if s := w.Scheduler(); s != nil {
+		return s.rand.Intn(n)
+	}
+	return rand.Intn(n)
+}
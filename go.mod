@@ -6,7 +6,6 @@ require github.com/chen3feng/stl4go v0.1.1
 
 require (
 	github.com/ebitengine/oto/v3 v3.1.0 // indirect
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/hajimehoshi/go-mp3 v0.3.4 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/magiconair/properties v1.8.7 // indirect
@@ -30,6 +29,7 @@ require (
 require (
 	github.com/ebitengine/purego v0.5.1 // indirect
 	github.com/ebitenui/ebitenui v0.5.5
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/hajimehoshi/ebiten/v2 v2.6.3
 	github.com/harbdog/raycaster-go v1.9.0